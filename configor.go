@@ -1,22 +1,29 @@
 package configor
 
 import (
+	"flag"
 	"fmt"
 	"io/fs"
-	"os"
 	"reflect"
 	"regexp"
+	"sync"
 	"time"
 )
 
 type Configor struct {
 	*Config
 	configModTimes map[string]time.Time
+	origins        map[string]Origin
+	loadingOrigins map[string]Origin
+	originsMutex   sync.RWMutex
+	fileCache      map[string]*configurationFile
+	fileCacheMutex sync.Mutex
 }
 
 type Config struct {
 	Environment        string
 	ENVPrefix          string
+	EnvNamer           EnvNamer
 	Debug              bool
 	Verbose            bool
 	Silent             bool
@@ -31,6 +38,81 @@ type Config struct {
 
 	// You can use embed.FS or any other fs.FS to load configs from. Default - use "os" package
 	FS fs.FS
+
+	// IncludeKey is the top level key of yaml and json configuration files to include other files, default - `include`,
+	// set to "-" to disable include directives, they are also disabled if the config struct has a field mapping to the key
+	IncludeKey string
+
+	// Interpolate resolves `${ENV_VAR}`, `${ENV_VAR:-fallback}` and `${self.DB.Host}` references in string values of
+	// configuration files, disabled by default, so existing files with literal `${` are loaded unchanged
+	Interpolate bool
+
+	// DefaultFuncs are named default providers, used by fields tagged with `default:"func:name"`
+	DefaultFuncs map[string]DefaultFunc
+
+	// AllowEmptyEnv loads empty shell environments, like `CONFIGOR_FEATURE_FLAG=`, to clear values,
+	// by default empty environments are ignored, same as unset ones, could be enabled per field with
+	// tag `env_allow_empty:"true"`
+	AllowEmptyEnv bool
+
+	// FlagSet owns flags bound by BindFlags, if set, flags bound to the loading config in FlagSet are applied
+	// instead of the package registry, so bindings live as long as FlagSet
+	FlagSet *flag.FlagSet
+
+	// Sources are loaded in order, later sources overwrite values of earlier sources, default - DefaultSources()
+	Sources []Source
+
+	// LookupEnv is used to look up shell environments, default - use os.LookupEnv
+	LookupEnv func(key string) (string, bool)
+	// Environ returns shell environments in the form "key=value", used to find map keys and slice indexes in env names,
+	// default - use os.Environ if LookupEnv is not set
+	Environ func() []string
+
+	// SecretProviders resolve string values like `file:///run/secrets/db_password` after all sources are loaded,
+	// keyed by scheme of references, values with schemes not registered are kept as they are
+	SecretProviders map[string]SecretProvider
+
+	// ErrorOnMissingFiles returns *MissingFileError if a configuration file, its environment variants and example
+	// variant can't be found, files marked with Optional are skipped
+	ErrorOnMissingFiles bool
+
+	// ExampleSuffix is the suffix of example files used if configuration files are missing, default - `example`,
+	// like `config.example.yml`
+	ExampleSuffix string
+	// ExampleEnvironments are environments allowed to use example files, like []string{"development", "test"},
+	// default - allow all environments, an empty non-nil slice disables example files
+	ExampleEnvironments []string
+	// ErrorOnExampleFallback returns *ExampleFallbackError instead of a warning if an example file would be used
+	ErrorOnExampleFallback bool
+
+	// SearchPaths are directories to find relative configuration files in order, like "./", "$XDG_CONFIG_HOME/app",
+	// "/etc/app", files are loaded from the first directory containing them or their environment or example variants
+	SearchPaths []string
+
+	// EnvironmentDetectors detect the environment in order if Environment is not set, default - DefaultEnvironmentDetectors()
+	EnvironmentDetectors []EnvironmentDetector
+	// EnvironmentParents declares environments inheriting from other environments, like {"production-eu": "production"},
+	// configuration files of parents are loaded before files of the environment
+	EnvironmentParents map[string]string
+	// EnvironmentSeparator declares environments inheriting by naming convention, like "-" for `production-eu` inheriting
+	// from `production`, default - disabled
+	EnvironmentSeparator string
+	// HostOverride loads `config.<hostname>.yml` after environment variants of `config.yml`, to pin per-host tweaks
+	HostOverride bool
+	// LocalOverride loads `config.local.yml` after all other variants of `config.yml`, for developer overrides not
+	// committed to the repository
+	LocalOverride bool
+	// Hostname is used to find host overrides and detect environments by hostname, default - use os.Hostname
+	Hostname string
+	// AllowedEnvironments returns error when loading configurations in environments not listed, default - allow all
+	AllowedEnvironments []string
+
+	// Logger receives diagnostics of debug mode, verbose mode and warnings, like *slog.Logger, default - print to stdout
+	Logger Logger
+
+	// EncryptionKey is the 32 bytes key used to decrypt values like `ENC[AES256_GCM,...]` in configuration files,
+	// default - use base64 encoded key from env CONFIGOR_ENCRYPTION_KEY
+	EncryptionKey []byte
 }
 
 // New initialize a Configor
@@ -39,15 +121,17 @@ func New(config *Config) *Configor {
 		config = &Config{}
 	}
 
-	if os.Getenv("CONFIGOR_DEBUG_MODE") != "" {
+	configor := &Configor{Config: config}
+
+	if configor.getenv("CONFIGOR_DEBUG_MODE") != "" {
 		config.Debug = true
 	}
 
-	if os.Getenv("CONFIGOR_VERBOSE_MODE") != "" {
+	if configor.getenv("CONFIGOR_VERBOSE_MODE") != "" {
 		config.Verbose = true
 	}
 
-	if os.Getenv("CONFIGOR_SILENT_MODE") != "" {
+	if configor.getenv("CONFIGOR_SILENT_MODE") != "" {
 		config.Silent = true
 	}
 
@@ -55,7 +139,7 @@ func New(config *Config) *Configor {
 		config.AutoReloadInterval = time.Second
 	}
 
-	return &Configor{Config: config}
+	return configor
 }
 
 var testRegexp = regexp.MustCompile("_test|(\\.test$)")
@@ -63,12 +147,15 @@ var testRegexp = regexp.MustCompile("_test|(\\.test$)")
 // GetEnvironment get environment
 func (configor *Configor) GetEnvironment() string {
 	if configor.Environment == "" {
-		if env := os.Getenv("CONFIGOR_ENV"); env != "" {
-			return env
+		detectors := configor.Config.EnvironmentDetectors
+		if detectors == nil {
+			detectors = DefaultEnvironmentDetectors()
 		}
 
-		if testRegexp.MatchString(os.Args[0]) {
-			return "test"
+		for _, detector := range detectors {
+			if env, ok := detector(configor); ok {
+				return env
+			}
 		}
 
 		return "development"
@@ -89,23 +176,29 @@ func (configor *Configor) Load(config interface{}, files ...string) (err error)
 	if !defaultValue.CanAddr() {
 		return fmt.Errorf("Config %v should be addressable", config)
 	}
-	err, _ = configor.load(config, false, files...)
+	flags := configor.getFlagBindings(config)
+
+	// reload from a copy of the value before loading, so merged fields don't merge loaded values again
+	var snapshot reflect.Value
+	if configor.Config.AutoReload {
+		snapshot = cloneValue(defaultValue)
+	}
+	err, _ = configor.load(config, flags, false, files...)
 
 	if configor.Config.AutoReload {
 		go func() {
 			timer := time.NewTimer(configor.Config.AutoReloadInterval)
 			for range timer.C {
 				reflectPtr := reflect.New(reflect.ValueOf(config).Elem().Type())
-				reflectPtr.Elem().Set(defaultValue)
+				reflectPtr.Elem().Set(cloneValue(snapshot))
 
-				var changed bool
-				if err, changed = configor.load(reflectPtr.Interface(), true, files...); err == nil && changed {
+				if err, changed := configor.load(reflectPtr.Interface(), flags, true, files...); err == nil && changed {
 					reflect.ValueOf(config).Elem().Set(reflectPtr.Elem())
 					if configor.Config.AutoReloadCallback != nil {
 						configor.Config.AutoReloadCallback(config)
 					}
 				} else if err != nil {
-					fmt.Printf("Failed to reload configuration from %v, got error %v\n", files, err)
+					configor.logger().Error("Failed to reload configuration", "files", stripOptionalFiles(files), "error", err)
 				}
 				timer.Reset(configor.Config.AutoReloadInterval)
 			}
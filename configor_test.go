@@ -3,11 +3,21 @@ package configor
 import (
 	"bytes"
 	"embed"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
 	"io/ioutil"
+	"net"
+	"net/url"
 	"os"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
@@ -696,3 +706,1736 @@ func TestLoad_FS(t *testing.T) {
 		t.Error("expected to have foo: bar in config")
 	}
 }
+
+func TestComputedDefaultValue(t *testing.T) {
+	type computedConfig struct {
+		APPName    string
+		Host       string `default:"${CONFIGOR_TEST_HOSTNAME}"`
+		WorkerName string `default:"{{.APPName}}-worker"`
+		Workers    int    `default:"func:workers"`
+		Port       uint   `default:"3306"`
+	}
+
+	file, err := ioutil.TempFile("/tmp", "configor.*.yml")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	file.WriteString("appname: myapp\n")
+
+	os.Setenv("CONFIGOR_TEST_HOSTNAME", "example.org")
+	defer os.Setenv("CONFIGOR_TEST_HOSTNAME", "")
+
+	var result computedConfig
+	err = New(&Config{DefaultFuncs: map[string]DefaultFunc{
+		"workers": func(config interface{}) (interface{}, error) {
+			return len(config.(*computedConfig).APPName), nil
+		},
+	}}).Load(&result, file.Name())
+	if err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	expected := computedConfig{APPName: "myapp", Host: "example.org", WorkerName: "myapp-worker", Workers: 5, Port: 3306}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("computed defaults should be set after loading files, expected %+v, got %+v", expected, result)
+	}
+
+	if err := New(nil).Load(&computedConfig{}, file.Name()); err == nil {
+		t.Errorf("Should get error when default func is not registered")
+	}
+}
+
+func TestInterpolationInConfigFiles(t *testing.T) {
+	type interpolationConfig struct {
+		Domain  string
+		Home    string
+		Backend string
+		Escaped string
+		DB      struct {
+			Host string
+			Port int
+			URL  string
+		}
+		Hosts []string
+		Any   map[string]interface{}
+	}
+
+	file, err := ioutil.TempFile("/tmp", "configor.*.yml")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	file.WriteString(`
+domain: example.org
+home: ${CONFIGOR_TEST_HOME}
+backend: ${CONFIGOR_TEST_BACKEND:-backend.local}
+escaped: $${NOT_A_REFERENCE}
+db:
+  host: db.${self.Domain}
+  port: 5432
+  url: postgres://${self.db.host}:${self.DB.Port}
+hosts:
+  - ${self.DB.Host}
+any:
+  x:
+    y: ${CONFIGOR_TEST_HOME}
+`)
+
+	os.Setenv("CONFIGOR_TEST_HOME", "/home/configor")
+	defer os.Setenv("CONFIGOR_TEST_HOME", "")
+
+	var literal interpolationConfig
+	if err := Load(&literal, file.Name()); err != nil {
+		t.Fatalf("No error should happen when load configurations without interpolation, but got %v", err)
+	}
+
+	if literal.Home != "${CONFIGOR_TEST_HOME}" || literal.Escaped != "$${NOT_A_REFERENCE}" {
+		t.Errorf("references should be kept as they are if interpolation is not enabled, got %+v", literal)
+	}
+
+	configor := New(&Config{Interpolate: true})
+	var result interpolationConfig
+	if err := configor.Load(&result, file.Name()); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if result.Home != "/home/configor" || result.Backend != "backend.local" || result.Escaped != "${NOT_A_REFERENCE}" {
+		t.Errorf("env references should be resolved, got %+v", result)
+	}
+
+	if result.DB.Host != "db.example.org" || result.DB.URL != "postgres://db.example.org:5432" || result.Hosts[0] != "db.example.org" {
+		t.Errorf("self references should be resolved, got %+v", result)
+	}
+
+	if y := result.Any["x"].(map[string]interface{})["y"]; y != "/home/configor" {
+		t.Errorf("references in interface values should be resolved, got %v", y)
+	}
+
+	ioutil.WriteFile(file.Name(), []byte("domain: ${self.DB.Host}\ndb:\n  host: db.${self.Domain}\n"), 0644)
+	if err := configor.Load(&interpolationConfig{}, file.Name()); err == nil || !strings.Contains(err.Error(), "reference cycle") {
+		t.Errorf("Should get reference cycle error, but got %v", err)
+	}
+
+	ioutil.WriteFile(file.Name(), []byte("domain: ${CONFIGOR_TEST_UNSET_ENV}\n"), 0644)
+	err = configor.Load(&interpolationConfig{}, file.Name())
+	if interpolationErr, ok := err.(*InterpolationError); !ok || interpolationErr.File != file.Name() || interpolationErr.Key != "Domain" {
+		t.Errorf("Should get InterpolationError with file and key of unresolved reference, but got %v", err)
+	}
+}
+
+func TestIncludeConfigurationFiles(t *testing.T) {
+	type includeConfig struct {
+		APPName string
+		Host    string
+		Port    int
+		User    string
+		Secret  string
+	}
+
+	dir, err := ioutil.TempDir("/tmp", "configor")
+	if err != nil {
+		t.Fatal("Could not create temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(dir+"/secrets", 0755)
+	ioutil.WriteFile(dir+"/common.yml", []byte("appname: common\nhost: localhost\nport: 80\n"), 0644)
+	ioutil.WriteFile(dir+"/common.test.yml", []byte("port: 8080\n"), 0644)
+	ioutil.WriteFile(dir+"/secrets/a.yml", []byte("user: a\nsecret: a\n"), 0644)
+	ioutil.WriteFile(dir+"/secrets/b.yml", []byte("secret: b\n"), 0644)
+	ioutil.WriteFile(dir+"/config.yml", []byte("include: [common.yml, secrets/*.yml]\nappname: configor\n"), 0644)
+
+	var result includeConfig
+	if err := New(&Config{ErrorOnUnmatchedKeys: true}).Load(&result, dir+"/config.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := (includeConfig{APPName: "configor", Host: "localhost", Port: 8080, User: "a", Secret: "b"}); result != expected {
+		t.Errorf("included files should be loaded before the including file, expected %+v, got %+v", expected, result)
+	}
+
+	ioutil.WriteFile(dir+"/config.toml", []byte("@import \"common.yml\"\nAPPName = \"toml\"\n"), 0644)
+	result = includeConfig{}
+	if err := New(&Config{ErrorOnUnmatchedKeys: true}).Load(&result, dir+"/config.toml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if result.APPName != "toml" || result.Host != "localhost" {
+		t.Errorf("toml files should import files, got %+v", result)
+	}
+
+	type includeFieldConfig struct {
+		APPName string
+		Include []string
+	}
+
+	var fieldResult includeFieldConfig
+	if err := New(&Config{}).Load(&fieldResult, dir+"/config.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := []string{"common.yml", "secrets/*.yml"}; fieldResult.APPName != "configor" || !reflect.DeepEqual(fieldResult.Include, expected) {
+		t.Errorf("include should be loaded as value if the struct has a field mapping to it, got %+v", fieldResult)
+	}
+
+	ioutil.WriteFile(dir+"/imports.yml", []byte("imports: [common.yml]\ninclude: [a, b]\n"), 0644)
+	fieldResult = includeFieldConfig{}
+	if err := New(&Config{IncludeKey: "imports"}).Load(&fieldResult, dir+"/imports.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if fieldResult.APPName != "common" || len(fieldResult.Include) != 2 {
+		t.Errorf("should include files with custom include key, got %+v", fieldResult)
+	}
+
+	ioutil.WriteFile(dir+"/missing.yml", []byte("include: [missing/*.yml]\nappname: missing\n"), 0644)
+	logger := &testLogger{}
+	if err := New(&Config{Logger: logger}).Load(&includeConfig{}, dir+"/missing.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if record := logger.find("warn", "Failed to find included configuration"); record == nil || record.args[1] != "missing/*.yml" {
+		t.Errorf("should warn includes matching nothing, but got %+v", logger.records)
+	}
+
+	ioutil.WriteFile(dir+"/common.yml", []byte("include: config.yml\n"), 0644)
+	if err := Load(&includeConfig{}, dir+"/config.yml"); err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Should get include cycle error, but got %v", err)
+	}
+}
+
+type countingFS struct {
+	fs.FS
+	opens map[string]int
+}
+
+func (f *countingFS) Open(name string) (fs.File, error) {
+	f.opens[name]++
+	return f.FS.Open(name)
+}
+
+func (f *countingFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.FS, name)
+}
+
+func TestReadConfigurationFilesOnce(t *testing.T) {
+	type onceConfig struct {
+		APPName string
+		Host    string
+		Port    int
+	}
+
+	dir, err := ioutil.TempDir("/tmp", "configor")
+	if err != nil {
+		t.Fatal("Could not create temp dir")
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/config.yml", []byte("include: db.yml\nappname: configor\n"), 0644)
+	ioutil.WriteFile(dir+"/db.yml", []byte("host: localhost\nport: 5432\n"), 0644)
+
+	countingFS := &countingFS{FS: os.DirFS(dir), opens: map[string]int{}}
+	configor := New(&Config{FS: countingFS})
+	for i := 0; i < 2; i++ {
+		var result onceConfig
+		if err := configor.Load(&result, "config.yml"); err != nil || result.APPName != "configor" || result.Port != 5432 {
+			t.Fatalf("should load configurations, but got %+v, %v", result, err)
+		}
+
+		if origin, _ := configor.Origin("Port"); origin.File != "db.yml" || origin.Line != 2 {
+			t.Errorf("should record origins, but got %v", origin)
+		}
+	}
+
+	if !reflect.DeepEqual(countingFS.opens, map[string]int{"config.yml": 1, "db.yml": 1}) {
+		t.Errorf("unchanged files should be read once, but got %v", countingFS.opens)
+	}
+
+	ioutil.WriteFile(dir+"/db.yml", []byte("host: localhost\nport: 15432\n"), 0644)
+	os.Chtimes(dir+"/db.yml", time.Now(), time.Now().Add(time.Second))
+
+	var result onceConfig
+	if err := configor.Load(&result, "config.yml"); err != nil || result.Port != 15432 || countingFS.opens["db.yml"] != 2 {
+		t.Errorf("changed files should be read again, but got %+v, %v, %v", result, err, countingFS.opens)
+	}
+}
+
+func TestLoadGlobAndDirectoryConfigurationFiles(t *testing.T) {
+	type confdConfig struct {
+		APPName string
+		Host    string
+		Port    int
+	}
+
+	dir, err := ioutil.TempDir("/tmp", "configor")
+	if err != nil {
+		t.Fatal("Could not create temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(dir+"/conf.d", 0755)
+	ioutil.WriteFile(dir+"/conf.d/10-app.yml", []byte("appname: app\nhost: localhost\nport: 80\n"), 0644)
+	ioutil.WriteFile(dir+"/conf.d/20-host.yml", []byte("host: example.org\n"), 0644)
+	ioutil.WriteFile(dir+"/conf.d/20-host.test.yml", []byte("port: 8080\n"), 0644)
+	ioutil.WriteFile(dir+"/conf.d/20-host.production.yml", []byte("port: 443\n"), 0644)
+	ioutil.WriteFile(dir+"/conf.d/README.md", []byte("# not a configuration file\n"), 0644)
+
+	for _, file := range []string{dir + "/conf.d", dir + "/conf.d/*.yml"} {
+		var result confdConfig
+		if err := New(&Config{ErrorOnUnmatchedKeys: true}).Load(&result, file); err != nil {
+			t.Fatalf("No error should happen when load configurations from %v, but got %v", file, err)
+		}
+
+		if expected := (confdConfig{APPName: "app", Host: "example.org", Port: 8080}); result != expected {
+			t.Errorf("files should be merged in sorted order when load from %v, expected %+v, got %+v", file, expected, result)
+		}
+	}
+
+	var result confdConfig
+	if err := New(&Config{FS: os.DirFS(dir)}).Load(&result, "conf.d"); err != nil || result.Host != "example.org" {
+		t.Errorf("directories should be expanded with Config.FS, got %+v, %v", result, err)
+	}
+
+	os.MkdirAll(dir+"/variants.d", 0755)
+	ioutil.WriteFile(dir+"/variants.d/db.development.yml", []byte("host: dev\n"), 0644)
+	ioutil.WriteFile(dir+"/variants.d/db.production.yml", []byte("host: prod\n"), 0644)
+	ioutil.WriteFile(dir+"/variants.d/db.qa.yml", []byte("port: 8000\n"), 0644)
+
+	for env, expected := range map[string]confdConfig{
+		"development": {Host: "dev"},
+		"production":  {Host: "prod"},
+		"staging":     {},
+		"qa":          {Port: 8000},
+	} {
+		result = confdConfig{}
+		if err := New(&Config{Environment: env, AllowedEnvironments: []string{"development", "production", "staging", "qa"}}).Load(&result, dir+"/variants.d"); err != nil {
+			t.Fatalf("No error should happen when load configurations, but got %v", err)
+		}
+
+		if result != expected {
+			t.Errorf("variants without base files should only be loaded if selected by environment %v, expected %+v, got %+v", env, expected, result)
+		}
+	}
+}
+
+func TestMergeStrategies(t *testing.T) {
+	type upstream struct {
+		Host    string
+		Timeout int
+	}
+
+	type mergeConfig struct {
+		Hosts     []string            `merge:"append"`
+		Tags      []string            `merge:"replace"`
+		Labels    map[string]string   `merge:"replace"`
+		Upstreams map[string]upstream `merge:"deep"`
+		Contacts  []struct {
+			Name  string
+			Email string
+		} `merge:"key=Name"`
+	}
+
+	dir, err := ioutil.TempDir("/tmp", "configor")
+	if err != nil {
+		t.Fatal("Could not create temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(dir+"/base.yml", []byte(`
+hosts: [a, b]
+tags: [x]
+labels: {team: core, tier: web}
+upstreams:
+  billing: {host: billing.local, timeout: 5}
+contacts:
+  - {name: jinzhu, email: jinzhu@example.org}
+`), 0644)
+	ioutil.WriteFile(dir+"/override.json", []byte(`{
+		"Hosts": ["c"],
+		"Labels": {"team": "platform"},
+		"Upstreams": {"billing": {"Timeout": 10}, "search": {"Host": "search.local"}},
+		"Contacts": [{"Name": "jinzhu", "Email": "wosmvp@gmail.com"}, {"Name": "configor"}]
+	}`), 0644)
+
+	var result mergeConfig
+	if err := Load(&result, dir+"/override.json", dir+"/base.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Hosts, []string{"a", "b", "c"}) || !reflect.DeepEqual(result.Tags, []string{"x"}) {
+		t.Errorf("slices should be merged with merge strategies, got %+v", result)
+	}
+
+	if !reflect.DeepEqual(result.Labels, map[string]string{"team": "platform"}) {
+		t.Errorf("maps should be replaced with replace strategy, got %+v", result.Labels)
+	}
+
+	if expected := map[string]upstream{"billing": {Host: "billing.local", Timeout: 10}, "search": {Host: "search.local"}}; !reflect.DeepEqual(result.Upstreams, expected) {
+		t.Errorf("maps should be merged deeply with deep strategy, got %+v", result.Upstreams)
+	}
+
+	if len(result.Contacts) != 2 || result.Contacts[0].Email != "wosmvp@gmail.com" || result.Contacts[1].Name != "configor" {
+		t.Errorf("slices should be merged by key, got %+v", result.Contacts)
+	}
+
+	var defaultsConfig struct {
+		Hosts  []string          `default:"[localhost]" merge:"append"`
+		Labels map[string]string `default:"{owner: nobody}" merge:"deep"`
+		Tags   []string          `default:"[default]" merge:"append"`
+	}
+	if err := Load(&defaultsConfig, dir+"/override.json", dir+"/base.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if !reflect.DeepEqual(defaultsConfig.Hosts, []string{"a", "b", "c"}) || !reflect.DeepEqual(defaultsConfig.Labels, map[string]string{"team": "platform", "tier": "web"}) {
+		t.Errorf("defaults should be replaced by files instead of merged, got %+v", defaultsConfig)
+	}
+
+	if !reflect.DeepEqual(defaultsConfig.Tags, []string{"x"}) {
+		t.Errorf("defaults should be replaced by files instead of merged, got %+v", defaultsConfig.Tags)
+	}
+
+	type server struct {
+		Name    string
+		Enabled bool
+		Port    int
+	}
+
+	var zeroConfig struct {
+		Server  server   `merge:"deep"`
+		Servers []server `merge:"key=Name"`
+	}
+	ioutil.WriteFile(dir+"/zero-base.yml", []byte("server: {enabled: true, port: 1}\nservers: [{name: x, enabled: true, port: 80}]\n"), 0644)
+	ioutil.WriteFile(dir+"/zero-override.yml", []byte("server: {enabled: false, port: 2}\nservers: [{name: x, enabled: false}]\n"), 0644)
+	if err := Load(&zeroConfig, dir+"/zero-override.yml", dir+"/zero-base.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := (server{Port: 2}); zeroConfig.Server != expected {
+		t.Errorf("zero values present in later files should be merged, expected %+v, got %+v", expected, zeroConfig.Server)
+	}
+
+	if expected := []server{{Name: "x", Port: 80}}; !reflect.DeepEqual(zeroConfig.Servers, expected) {
+		t.Errorf("zero values present in later files should be merged by key, expected %+v, got %+v", expected, zeroConfig.Servers)
+	}
+
+	var invalidConfig struct {
+		Name string `merge:"append"`
+	}
+	if err := Load(&invalidConfig, dir+"/base.yml"); err == nil {
+		t.Errorf("Should get error when merge strategy is invalid")
+	}
+}
+
+func TestAutoReloadMergeFields(t *testing.T) {
+	type reloadConfig struct {
+		Hosts []string `merge:"append"`
+	}
+
+	dir, err := ioutil.TempDir("/tmp", "configor")
+	if err != nil {
+		t.Fatal("Could not create temp dir")
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/a.yml", []byte("hosts: [a]\n"), 0644)
+	ioutil.WriteFile(dir+"/b.yml", []byte("hosts: [b]\n"), 0644)
+
+	reloaded := make(chan []string, 10)
+	configor := New(&Config{AutoReload: true, AutoReloadInterval: 10 * time.Millisecond, Logger: &testLogger{}, AutoReloadCallback: func(config interface{}) {
+		reloaded <- append([]string{}, config.(*reloadConfig).Hosts...)
+	}})
+
+	// result is written by the reload goroutine, check values passed to the callback only
+	var result reloadConfig
+	if err := configor.Load(&result, dir+"/b.yml", dir+"/a.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		os.Chtimes(dir+"/a.yml", time.Now(), time.Now().Add(time.Duration(i)*time.Second))
+		select {
+		case hosts := <-reloaded:
+			if !reflect.DeepEqual(hosts, []string{"a", "b"}) {
+				t.Errorf("reloading should not append loaded hosts again, but got %v", hosts)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("configuration should be reloaded after files changed")
+		}
+	}
+}
+
+func TestEnvNamer(t *testing.T) {
+	type namerConfig struct {
+		APPName string
+		DB      struct {
+			MaxOpenConns int
+		}
+	}
+
+	os.Setenv("CONFIGOR_APP_NAME", "snake")
+	os.Setenv("CONFIGOR_DB_MAX_OPEN_CONNS", "10")
+	os.Setenv("CONFIGOR__DB__MAX_OPEN_CONNS", "20")
+	os.Setenv("configor_db_max_open_conns", "30")
+	defer os.Unsetenv("CONFIGOR_APP_NAME")
+	defer os.Unsetenv("CONFIGOR_DB_MAX_OPEN_CONNS")
+	defer os.Unsetenv("CONFIGOR__DB__MAX_OPEN_CONNS")
+	defer os.Unsetenv("configor_db_max_open_conns")
+
+	var result namerConfig
+	New(&Config{EnvNamer: SnakeCaseEnvNamer("_")}).Load(&result)
+	if result.APPName != "snake" || result.DB.MaxOpenConns != 10 {
+		t.Errorf("should load env with snake case names, got %+v", result)
+	}
+
+	result = namerConfig{}
+	New(&Config{EnvNamer: SnakeCaseEnvNamer("__")}).Load(&result)
+	if result.DB.MaxOpenConns != 20 {
+		t.Errorf("should load env with custom separator, got %+v", result)
+	}
+
+	result = namerConfig{}
+	New(&Config{EnvNamer: LowerCaseEnvNamer(SnakeCaseEnvNamer("_"))}).Load(&result)
+	if result.DB.MaxOpenConns != 30 {
+		t.Errorf("should load env with lower case names, got %+v", result)
+	}
+
+	for name, expected := range map[string]string{"MaxOpenConns": "Max_Open_Conns", "APPName": "APP_Name", "DBPassword": "DB_Password", "Test2Ele1": "Test2_Ele1", "ID": "ID"} {
+		if got := toSnakeCase(name); got != expected {
+			t.Errorf("snake case of %v should be %v, got %v", name, expected, got)
+		}
+	}
+}
+
+type testLevel int
+
+func (level *testLevel) UnmarshalYAML(value *yaml.Node) error {
+	for i, name := range []string{"debug", "info", "warn"} {
+		if value.Value == name {
+			*level = testLevel(i)
+			return nil
+		}
+	}
+
+	var i int
+	if err := value.Decode(&i); err != nil {
+		return err
+	}
+	*level = testLevel(i)
+	return nil
+}
+
+type testPriority uint
+
+func (priority *testPriority) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err != nil {
+		return err
+	}
+
+	if name == "high" {
+		*priority = 10
+		return nil
+	}
+	return fmt.Errorf("unknown priority %v", name)
+}
+
+func TestNativeEnvValues(t *testing.T) {
+	type nativeEnvConfig struct {
+		Hosts    []string
+		Ports    []int `env_separator:";"`
+		Labels   map[string]string
+		Limits   map[string]int
+		Timeout  time.Duration
+		Deadline time.Time
+		IP       net.IP
+		Endpoint url.URL
+		Proxy    *url.URL
+		Level    *int
+		LogLevel testLevel
+		Priority testPriority
+	}
+
+	envs := map[string]string{
+		"CONFIGOR_HOSTS":    "a, b,c",
+		"CONFIGOR_PORTS":    "80;443",
+		"CONFIGOR_LABELS":   "team=core,tier=web",
+		"CONFIGOR_LIMITS":   "{cpu: 2, memory: 512}",
+		"CONFIGOR_TIMEOUT":  "5s",
+		"CONFIGOR_DEADLINE": "2020-01-02T03:04:05Z",
+		"CONFIGOR_IP":       "10.0.0.1",
+		"CONFIGOR_ENDPOINT": "https://example.org/api",
+		"CONFIGOR_PROXY":    "http://proxy.local:3128",
+		"CONFIGOR_LEVEL":    "3",
+		"CONFIGOR_LOGLEVEL": "warn",
+		"CONFIGOR_PRIORITY": "high",
+	}
+	for key, value := range envs {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+
+	var result nativeEnvConfig
+	if err := Load(&result); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Hosts, []string{"a", "b", "c"}) || !reflect.DeepEqual(result.Ports, []int{80, 443}) {
+		t.Errorf("should load slices from separated env values, got %+v, %+v", result.Hosts, result.Ports)
+	}
+
+	if !reflect.DeepEqual(result.Labels, map[string]string{"team": "core", "tier": "web"}) || !reflect.DeepEqual(result.Limits, map[string]int{"cpu": 2, "memory": 512}) {
+		t.Errorf("should load maps from env values, got %+v, %+v", result.Labels, result.Limits)
+	}
+
+	if result.Timeout != 5*time.Second || !result.Deadline.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) || !result.IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("should load durations, times and IPs from env values, got %+v", result)
+	}
+
+	if result.Endpoint.Host != "example.org" || result.Proxy == nil || result.Proxy.Host != "proxy.local:3128" || result.Level == nil || *result.Level != 3 {
+		t.Errorf("should load urls and pointers from env values, got %+v", result)
+	}
+
+	if result.LogLevel != 2 || result.Priority != 10 {
+		t.Errorf("should load values of types implementing yaml unmarshalers, got %+v, %+v", result.LogLevel, result.Priority)
+	}
+
+	os.Setenv("CONFIGOR_TIMEOUT", "5 seconds")
+	if err := Load(&nativeEnvConfig{}); err == nil {
+		t.Errorf("Should get error when env value is invalid")
+	}
+}
+
+func TestMapValuesFromEnv(t *testing.T) {
+	type upstream struct {
+		Host    string
+		Timeout time.Duration `default:"1s"`
+	}
+
+	type mapEnvConfig struct {
+		Upstreams map[string]upstream
+		Proxies   map[string]*upstream
+		Weights   map[string]int
+	}
+
+	file, err := ioutil.TempFile("/tmp", "configor.*.yml")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	file.WriteString("upstreams:\n  billing: {host: billing.local, timeout: 5s}\nproxies:\n  eu: {host: eu.local}\nweights:\n  a: 1\n")
+
+	envs := map[string]string{
+		"CONFIGOR_UPSTREAMS_BILLING_TIMEOUT": "10s",
+		"CONFIGOR_UPSTREAMS_SEARCH_HOST":     "search.local",
+		"CONFIGOR_PROXIES_EU_TIMEOUT":        "3s",
+		"CONFIGOR_PROXIES_US_HOST":           "us.local",
+		"CONFIGOR_WEIGHTS_A":                 "2",
+		"CONFIGOR_WEIGHTS_B":                 "3",
+	}
+	for key, value := range envs {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+
+	var result mapEnvConfig
+	if err := Load(&result, file.Name()); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := map[string]upstream{"billing": {Host: "billing.local", Timeout: 10 * time.Second}, "search": {Host: "search.local", Timeout: time.Second}}; !reflect.DeepEqual(result.Upstreams, expected) {
+		t.Errorf("should load map values from env, expected %+v, got %+v", expected, result.Upstreams)
+	}
+
+	if len(result.Proxies) != 2 || result.Proxies["eu"].Timeout != 3*time.Second || result.Proxies["us"].Host != "us.local" {
+		t.Errorf("should load map pointer values from env, got %+v", result.Proxies)
+	}
+
+	if !reflect.DeepEqual(result.Weights, map[string]int{"a": 2, "b": 3}) {
+		t.Errorf("should load map scalar values from env, got %+v", result.Weights)
+	}
+
+	type nestedUpstream struct {
+		Host string
+		DB   struct{ Host string }
+	}
+
+	os.Setenv("CONFIGOR_NESTED_X_DB_HOST", "db.local")
+	defer os.Unsetenv("CONFIGOR_NESTED_X_DB_HOST")
+
+	var nestedResult struct{ Nested map[string]nestedUpstream }
+	if err := Load(&nestedResult); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if len(nestedResult.Nested) != 1 || nestedResult.Nested["x"].DB.Host != "db.local" {
+		t.Errorf("should prefer the longest leaf path matching env names, got %+v", nestedResult.Nested)
+	}
+
+	os.Setenv("CONFIGOR_PORTS_80", "http")
+	os.Setenv("CONFIGOR_PORTS_ABC", "1")
+	defer os.Unsetenv("CONFIGOR_PORTS_80")
+	defer os.Unsetenv("CONFIGOR_PORTS_ABC")
+
+	var portsResult struct{ Ports map[int]string }
+	if err := Load(&portsResult); err != nil {
+		t.Fatalf("No error should happen when env names have keys of other types, but got %v", err)
+	}
+
+	if !reflect.DeepEqual(portsResult.Ports, map[int]string{80: "http"}) {
+		t.Errorf("should skip env names with keys of other types, got %+v", portsResult.Ports)
+	}
+}
+
+func TestSliceElementsFromEnv(t *testing.T) {
+	type contact struct {
+		Name  string
+		Email string `required:"true"`
+		Age   int
+	}
+
+	type sliceEnvConfig struct {
+		Contacts []contact
+	}
+
+	file, err := ioutil.TempFile("/tmp", "configor.*.yml")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	file.WriteString("contacts:\n  - {name: jinzhu, email: jinzhu@example.org}\n")
+
+	envs := map[string]string{
+		"CONFIGOR_CONTACTS_0_EMAIL": "wosmvp@gmail.com",
+		"CONFIGOR_CONTACTS_5_NAME":  "configor",
+		"CONFIGOR_CONTACTS_5_EMAIL": "configor@example.org",
+		"CONFIGOR_CONTACTS_3_EMAIL": "gorm@example.org",
+	}
+	for key, value := range envs {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+
+	var result sliceEnvConfig
+	if err := Load(&result, file.Name()); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	expected := []contact{{Name: "jinzhu", Email: "wosmvp@gmail.com"}, {Email: "gorm@example.org"}, {Name: "configor", Email: "configor@example.org"}}
+	if !reflect.DeepEqual(result.Contacts, expected) {
+		t.Errorf("should override and append slice elements from env, expected %+v, got %+v", expected, result.Contacts)
+	}
+
+	os.Setenv("CONFIGOR_CONTACTS_5_AGE", "unknown")
+	defer os.Unsetenv("CONFIGOR_CONTACTS_5_AGE")
+	if err := Load(&sliceEnvConfig{}, file.Name()); err == nil || !strings.Contains(err.Error(), "CONFIGOR_CONTACTS_5_AGE") {
+		t.Errorf("Should get error when slice element from env is invalid")
+	}
+}
+
+func TestLookupEnv(t *testing.T) {
+	type lookupEnvConfig struct {
+		APPName string
+		Weights map[string]int
+	}
+
+	envs := map[string]string{
+		"CONFIGOR_ENV":        "staging",
+		"CONFIGOR_ENV_PREFIX": "TENANT",
+		"TENANT_APPNAME":      "isolated",
+		"TENANT_WEIGHTS_A":    "1",
+	}
+
+	configor := New(&Config{
+		LookupEnv: func(key string) (string, bool) {
+			value, ok := envs[key]
+			return value, ok
+		},
+		Environ: func() []string {
+			var environ []string
+			for key, value := range envs {
+				environ = append(environ, key+"="+value)
+			}
+			return environ
+		},
+	})
+
+	os.Setenv("TENANT_APPNAME", "process")
+	defer os.Unsetenv("TENANT_APPNAME")
+
+	var result lookupEnvConfig
+	if err := configor.Load(&result); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if configor.GetEnvironment() != "staging" {
+		t.Errorf("environment should be looked up with LookupEnv, got %v", configor.GetEnvironment())
+	}
+
+	if result.APPName != "isolated" || !reflect.DeepEqual(result.Weights, map[string]int{"a": 1}) {
+		t.Errorf("values should be looked up with LookupEnv and Environ, got %+v", result)
+	}
+}
+
+func TestEmptyEnv(t *testing.T) {
+	type emptyEnvConfig struct {
+		Name        string
+		Description string `env_allow_empty:"true"`
+		FeatureFlag bool
+		Port        int
+	}
+
+	file, err := ioutil.TempFile("/tmp", "configor.*.yml")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	file.WriteString("name: configor\ndescription: configuration tool\nfeatureflag: true\nport: 80\n")
+
+	for _, key := range []string{"CONFIGOR_NAME", "CONFIGOR_DESCRIPTION", "CONFIGOR_FEATUREFLAG", "CONFIGOR_PORT"} {
+		os.Setenv(key, "")
+		defer os.Unsetenv(key)
+	}
+
+	var result emptyEnvConfig
+	if err := Load(&result, file.Name()); err != nil {
+		t.Errorf("should load configuration with empty env, got %v", err)
+	}
+	if expected := (emptyEnvConfig{Name: "configor", FeatureFlag: true, Port: 80}); result != expected {
+		t.Errorf("only fields with env_allow_empty tag should be cleared with empty env, expected %+v, got %+v", expected, result)
+	}
+
+	result = emptyEnvConfig{}
+	if err := New(&Config{AllowEmptyEnv: true}).Load(&result, file.Name()); err != nil {
+		t.Errorf("should load configuration with empty env when AllowEmptyEnv, got %v", err)
+	}
+	if expected := (emptyEnvConfig{}); result != expected {
+		t.Errorf("fields should be cleared with empty env when AllowEmptyEnv, expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestEnvAliasesAndDeprecatedNames(t *testing.T) {
+	type aliasConfig struct {
+		Password string `env:"CONFIGOR_TEST_DB_PASSWORD,CONFIGOR_TEST_DATABASE_PASSWORD" env_deprecated:"CONFIGOR_TEST_OLD_DB_PASS"`
+	}
+
+	envs := map[string]string{}
+	logger := &testLogger{}
+	configor := New(&Config{Logger: logger, LookupEnv: func(key string) (string, bool) {
+		value, ok := envs[key]
+		return value, ok
+	}})
+
+	for _, c := range []struct {
+		envs       map[string]string
+		expected   string
+		deprecated bool
+	}{
+		{map[string]string{"CONFIGOR_TEST_OLD_DB_PASS": "old"}, "old", true},
+		{map[string]string{"CONFIGOR_TEST_OLD_DB_PASS": "old", "CONFIGOR_TEST_DATABASE_PASSWORD": "alias"}, "alias", false},
+		{map[string]string{"CONFIGOR_TEST_OLD_DB_PASS": "old", "CONFIGOR_TEST_DATABASE_PASSWORD": "alias", "CONFIGOR_TEST_DB_PASSWORD": "new"}, "new", false},
+	} {
+		envs = c.envs
+		logger.records = nil
+		var result aliasConfig
+		if err := configor.Load(&result); err != nil || result.Password != c.expected {
+			t.Errorf("password should be %v with envs %v, got %v, %v", c.expected, c.envs, result.Password, err)
+		}
+
+		record := logger.find("warn", "Env is deprecated")
+		if c.deprecated {
+			expected := []interface{}{"env", "CONFIGOR_TEST_OLD_DB_PASS", "struct", "aliasConfig", "field", "Password", "replacement", "CONFIGOR_TEST_DB_PASSWORD, CONFIGOR_TEST_DATABASE_PASSWORD"}
+			if record == nil || !reflect.DeepEqual(record.args, expected) {
+				t.Errorf("should warn deprecated env with envs %v, but got %+v", c.envs, record)
+			}
+		} else if record != nil {
+			t.Errorf("should not warn deprecated env with envs %v, but got %+v", c.envs, record)
+		}
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	type flagConfig struct {
+		APPName string `default:"configor"`
+		Debug   bool
+		DB      struct {
+			Name         string `required:"true"`
+			Port         uint   `flag:"port" usage:"database port"`
+			MaxOpenConns int
+		}
+		Hosts   []string
+		Timeout time.Duration
+		Secret  string `flag:"-"`
+		Verbose *bool
+		Token   string `secret:"true"`
+	}
+
+	var result flagConfig
+	result.Token = "hunter2"
+	fs := flag.NewFlagSet("configor", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	if err := BindFlags(fs, &result); err != nil {
+		t.Fatalf("No error should happen when bind flags, but got %v", err)
+	}
+
+	if fs.Lookup("secret") != nil || fs.Lookup("port").Usage != "database port" || fs.Lookup("db-max-open-conns") == nil {
+		t.Errorf("flags should be defined with names and usages from tags")
+	}
+
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+	fs.SetOutput(ioutil.Discard)
+	if strings.Contains(usage.String(), "hunter2") || !strings.Contains(usage.String(), "(default ******)") {
+		t.Errorf("defaults of sensitive fields should be redacted, got %v", usage.String())
+	}
+
+	if err := fs.Parse([]string{"--db-name", "flag_db", "--port=5432", "--debug", "--hosts", "a,b", "--timeout", "5s", "--verbose", "--token", "s3cret"}); err != nil {
+		t.Fatalf("No error should happen when parse flags, but got %v", err)
+	}
+
+	os.Setenv("CONFIGOR_DB_NAME", "env_db")
+	os.Setenv("CONFIGOR_DB_MAXOPENCONNS", "10")
+	defer os.Unsetenv("CONFIGOR_DB_NAME")
+	defer os.Unsetenv("CONFIGOR_DB_MAXOPENCONNS")
+
+	if err := Load(&result); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if result.APPName != "configor" || !result.Debug || result.DB.Name != "flag_db" || result.DB.Port != 5432 || result.DB.MaxOpenConns != 10 {
+		t.Errorf("flags should be applied after env, got %+v", result)
+	}
+
+	if !reflect.DeepEqual(result.Hosts, []string{"a", "b"}) || result.Timeout != 5*time.Second {
+		t.Errorf("flags should be decoded like env values, got %+v", result)
+	}
+
+	if result.Verbose == nil || !*result.Verbose {
+		t.Errorf("pointers of bools should be bool flags, got %+v", result.Verbose)
+	}
+
+	if result.Token != "s3cret" || fs.Lookup("token").Value.String() != "******" {
+		t.Errorf("values of sensitive flags should be applied but redacted when printed, got %v", result.Token)
+	}
+
+	UnbindFlags(&result)
+	result = flagConfig{}
+	if err := Load(&result); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if result.DB.Name != "env_db" || result.DB.Port != 0 || result.Debug {
+		t.Errorf("flags should not be applied after unbind, got %+v", result)
+	}
+
+	result = flagConfig{}
+	if err := New(&Config{FlagSet: fs}).Load(&result); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if result.DB.Name != "flag_db" || result.DB.Port != 5432 || !result.Debug {
+		t.Errorf("flags bound in Config.FlagSet should be applied, got %+v", result)
+	}
+
+	var other flagConfig
+	if err := New(&Config{FlagSet: fs}).Load(&other); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if other.DB.Name != "env_db" || other.Debug {
+		t.Errorf("flags bound to other configs should not be applied, got %+v", other)
+	}
+
+	if err := fs.Parse([]string{"--port", "unknown"}); err == nil {
+		t.Errorf("Should get error when flag value is invalid")
+	}
+}
+
+func TestSources(t *testing.T) {
+	type sourcesConfig struct {
+		APPName string `default:"default"`
+		Host    string
+		Port    int
+	}
+
+	file, err := ioutil.TempFile("/tmp", "configor.*.yml")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	file.WriteString("host: file.local\nport: 80\n")
+
+	os.Setenv("CONFIGOR_HOST", "env.local")
+	os.Setenv("CONFIGOR_APPNAME", "env")
+	defer os.Unsetenv("CONFIGOR_HOST")
+	defer os.Unsetenv("CONFIGOR_APPNAME")
+
+	var result sourcesConfig
+	err = New(&Config{Sources: []Source{
+		Defaults(),
+		Env(),
+		SourceFunc(func(config interface{}) error {
+			config.(*sourcesConfig).Port = 8080
+			return nil
+		}),
+		Files(file.Name()),
+	}}).Load(&result)
+	if err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := (sourcesConfig{APPName: "env", Host: "file.local", Port: 80}); result != expected {
+		t.Errorf("files should overwrite env when loaded after env, expected %+v, got %+v", expected, result)
+	}
+
+	result = sourcesConfig{}
+	if err := New(&Config{Sources: []Source{Files(), SourceFunc(func(config interface{}) error {
+		return errors.New("custom source error")
+	})}}).Load(&result, file.Name()); err == nil || err.Error() != "custom source error" {
+		t.Errorf("Should get error of custom source, but got %v", err)
+	}
+
+	if expected := (sourcesConfig{Host: "file.local", Port: 80}); result != expected {
+		t.Errorf("only configured sources should be loaded, expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	type originConfig struct {
+		APPName string `default:"configor"`
+		Timeout int    `default:"30"`
+		DB      struct {
+			Host string
+			Port int
+		}
+		Upstreams map[string]struct{ Timeout int }
+		Contacts  []struct{ Name string }
+	}
+
+	file, err := ioutil.TempFile("/tmp", "configor.*.yml")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	file.WriteString("db:\n  host: file.local\n  port: 5432\ncontacts:\n  - name: jinzhu\n")
+
+	os.Setenv("CONFIGOR_DB_PORT", "3306")
+	os.Setenv("CONFIGOR_UPSTREAMS_BILLING_TIMEOUT", "5")
+	defer os.Unsetenv("CONFIGOR_DB_PORT")
+	defer os.Unsetenv("CONFIGOR_UPSTREAMS_BILLING_TIMEOUT")
+
+	var result originConfig
+	fs := flag.NewFlagSet("configor", flag.ContinueOnError)
+	if err := BindFlags(fs, &result); err != nil {
+		t.Fatalf("No error should happen when bind flags, but got %v", err)
+	}
+	if err := fs.Parse([]string{"--timeout", "10"}); err != nil {
+		t.Fatalf("No error should happen when parse flags, but got %v", err)
+	}
+
+	configor := New(&Config{})
+	if _, ok := configor.Origin("DB.Host"); ok {
+		t.Errorf("should have no origins before loading")
+	}
+
+	if err := configor.Load(&result, file.Name()); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	for key, expected := range map[string]Origin{
+		"APPName":                   {Kind: OriginDefault},
+		"Timeout":                   {Kind: OriginFlag, Flag: "timeout"},
+		"DB.Host":                   {Kind: OriginFile, File: file.Name(), Line: 2},
+		"db.host":                   {Kind: OriginFile, File: file.Name(), Line: 2},
+		"DB.Port":                   {Kind: OriginEnv, Env: "CONFIGOR_DB_PORT"},
+		"Upstreams.billing.Timeout": {Kind: OriginEnv, Env: "CONFIGOR_UPSTREAMS_BILLING_TIMEOUT"},
+		"Contacts.0.Name":           {Kind: OriginFile, File: file.Name(), Line: 5},
+	} {
+		if origin, ok := configor.Origin(key); !ok || origin != expected {
+			t.Errorf("origin of %v should be %v, but got %v", key, expected, origin)
+		}
+	}
+
+	if _, ok := configor.Origin("Upstreams.payment.Timeout"); ok {
+		t.Errorf("should have no origin for fields not loaded")
+	}
+}
+
+func TestOriginOfZeroValues(t *testing.T) {
+	type zeroConfig struct {
+		Debug bool
+		Port  int `json:"http_port" toml:"http_port"`
+		Name  string
+	}
+
+	for _, c := range []struct{ ext, base, override string }{
+		{".yml", "debug: true\nport: 80\nname: configor\n", "debug: false\nport: 0\n"},
+		{".json", `{"Debug": true, "http_port": 80, "Name": "configor"}`, `{"debug": false, "http_port": 0}`},
+		{".toml", "debug = true\nhttp_port = 80\nname = \"configor\"\n", "debug = false\nhttp_port = 0\n"},
+	} {
+		dir, err := ioutil.TempDir("/tmp", "configor")
+		if err != nil {
+			t.Fatal("Could not create temp dir")
+		}
+		defer os.RemoveAll(dir)
+
+		base, override := dir+"/base"+c.ext, dir+"/override"+c.ext
+		ioutil.WriteFile(base, []byte(c.base), 0644)
+		ioutil.WriteFile(override, []byte(c.override), 0644)
+
+		var result zeroConfig
+		configor := New(&Config{})
+		if err := configor.Load(&result, override, base); err != nil {
+			t.Fatalf("No error should happen when load configurations, but got %v", err)
+		}
+
+		if result.Debug || result.Port != 0 || result.Name != "configor" {
+			t.Errorf("zero values of %v should overwrite values of other files, got %+v", c.ext, result)
+		}
+
+		for key, file := range map[string]string{"Debug": override, "Port": override, "Name": base} {
+			if origin, ok := configor.Origin(key); !ok || origin.Kind != OriginFile || origin.File != file {
+				t.Errorf("origin of %v in %v files should be %v, but got %v", key, c.ext, file, origin)
+			}
+		}
+	}
+}
+
+func TestSecretProviders(t *testing.T) {
+	type secretConfig struct {
+		DB struct {
+			Password string
+			Token    *string
+		}
+		APIKeys  map[string]string
+		Endpoint string
+	}
+
+	secretFile, err := ioutil.TempFile("/tmp", "configor.*.secret")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(secretFile.Name())
+	defer secretFile.Close()
+	secretFile.WriteString("file-password\n")
+
+	file, err := ioutil.TempFile("/tmp", "configor.*.yml")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	file.WriteString("db:\n  password: file://" + secretFile.Name() + "\napikeys:\n  billing: vault:kv/data/app#billing\nendpoint: https://example.com\n")
+
+	os.Setenv("CONFIGOR_DB_TOKEN", "vault:kv/data/app#token")
+	defer os.Unsetenv("CONFIGOR_DB_TOKEN")
+
+	var result secretConfig
+	err = New(&Config{SecretProviders: map[string]SecretProvider{
+		"file":  FileSecretProvider{},
+		"vault": MemorySecretProvider{"kv/data/app#billing": "billing-key", "kv/data/app#token": "token"},
+	}}).Load(&result, file.Name())
+	if err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if result.DB.Password != "file-password" {
+		t.Errorf("secret should be read from file, but got %v", result.DB.Password)
+	}
+
+	if result.DB.Token == nil || *result.DB.Token != "token" {
+		t.Errorf("secret reference loaded from env should be resolved, but got %v", result.DB.Token)
+	}
+
+	if result.APIKeys["billing"] != "billing-key" {
+		t.Errorf("secret reference in map should be resolved, but got %v", result.APIKeys)
+	}
+
+	if result.Endpoint != "https://example.com" {
+		t.Errorf("values with schemes not registered should be kept, but got %v", result.Endpoint)
+	}
+
+	result = secretConfig{}
+	if err := New(&Config{}).Load(&result, file.Name()); err != nil || result.DB.Password != "file://"+secretFile.Name() {
+		t.Errorf("secret references should be kept without providers, but got %v, %v", result.DB.Password, err)
+	}
+
+	err = New(&Config{SecretProviders: map[string]SecretProvider{
+		"vault": MemorySecretProvider{},
+	}}).Load(&secretConfig{}, file.Name())
+	if err == nil || !strings.Contains(err.Error(), "failed to resolve secret of") {
+		t.Errorf("should return error if secret not found, but got %v", err)
+	}
+}
+
+func TestDumpSensitiveFields(t *testing.T) {
+	type dumpConfig struct {
+		APPName string
+		DB      struct {
+			User     string
+			Password string `secret:"true"`
+			Port     int    `sensitive:"true"`
+		}
+		Tokens []struct {
+			Name  string
+			Value string `secret:"true"`
+		}
+		Extra map[string]interface{}
+	}
+
+	var config dumpConfig
+	config.APPName = "configor"
+	config.DB.User = "root"
+	config.DB.Password = "p@ssw0rd"
+	config.Tokens = append(config.Tokens, struct {
+		Name  string
+		Value string `secret:"true"`
+	}{Name: "github", Value: "gh-token"})
+
+	dump := Dump(&config)
+	for _, secret := range []string{"p@ssw0rd", "gh-token"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("dump should not contain secret %v, but got %v", secret, dump)
+		}
+	}
+
+	for _, expected := range []string{`APPName:"configor"`, `User:"root"`, "Password:******", "Port:<unset>", `Name:"github"`, "Value:******", "Extra:map[string]interface {}(nil)"} {
+		if !strings.Contains(dump, expected) {
+			t.Errorf("dump should contain %v, but got %v", expected, dump)
+		}
+	}
+
+	type plainConfig struct{ Name string }
+	if dump := Dump(&plainConfig{Name: "configor"}); dump != fmt.Sprintf("%#v", &plainConfig{Name: "configor"}) {
+		t.Errorf("dump should be same as %%#v without sensitive fields, but got %v", dump)
+	}
+
+	os.Setenv("CONFIGOR_DB_PORT", "not-a-port")
+	defer os.Unsetenv("CONFIGOR_DB_PORT")
+	if err := New(&Config{}).Load(&dumpConfig{}); err == nil || strings.Contains(err.Error(), "not-a-port") {
+		t.Errorf("errors of sensitive fields should not contain values, but got %v", err)
+	}
+}
+
+func TestEncryptedValues(t *testing.T) {
+	type encryptedConfig struct {
+		APPName  string
+		Password string
+		Port     int
+		Debug    bool
+	}
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	password, err := Encrypt(key, `p@ss: "word"`)
+	if err != nil {
+		t.Fatalf("No error should happen when encrypt values, but got %v", err)
+	}
+	port, _ := Encrypt(key, 5432)
+	debug, _ := Encrypt(key, true)
+
+	for _, format := range []string{"yml", "json", "toml"} {
+		file, err := ioutil.TempFile("/tmp", "configor.*."+format)
+		if err != nil {
+			t.Fatal("Could not create temp file")
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+
+		switch format {
+		case "yml":
+			file.WriteString("appname: configor\npassword: " + password + "\nport: " + port + "\ndebug: " + debug + "\n")
+		case "json":
+			file.WriteString(`{"appname": "configor", "password": "` + password + `", "port": "` + port + `", "debug": "` + debug + `"}`)
+		case "toml":
+			file.WriteString("appname = \"configor\"\npassword = \"" + password + "\"\nport = \"" + port + "\"\ndebug = \"" + debug + "\"\n")
+		}
+
+		var result encryptedConfig
+		if err := New(&Config{EncryptionKey: key}).Load(&result, file.Name()); err != nil {
+			t.Fatalf("No error should happen when load encrypted %v file, but got %v", format, err)
+		}
+
+		if expected := (encryptedConfig{APPName: "configor", Password: `p@ss: "word"`, Port: 5432, Debug: true}); result != expected {
+			t.Errorf("encrypted values of %v file should be decrypted, expected %+v, got %+v", format, expected, result)
+		}
+	}
+
+	file, err := ioutil.TempFile("/tmp", "configor.*.yml")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	file.WriteString("password: " + password + "\n")
+
+	os.Setenv("CONFIGOR_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(key))
+	var result encryptedConfig
+	if err := New(&Config{}).Load(&result, file.Name()); err != nil || result.Password != `p@ss: "word"` {
+		t.Errorf("should decrypt values with key from env, but got %v, %v", result.Password, err)
+	}
+	os.Unsetenv("CONFIGOR_ENCRYPTION_KEY")
+
+	if err := New(&Config{}).Load(&encryptedConfig{}, file.Name()); err == nil {
+		t.Errorf("should return error if encryption key is not set")
+	}
+
+	if err := New(&Config{EncryptionKey: []byte("fedcba9876543210fedcba9876543210")}).Load(&encryptedConfig{}, file.Name()); err == nil {
+		t.Errorf("should return error if encryption key is wrong")
+	}
+
+	var embeddedConfig struct {
+		URL    string
+		Quoted string
+		Extra  map[string]interface{}
+		Port   int
+	}
+	file.Truncate(0)
+	file.Seek(0, 0)
+	file.WriteString("url: \"postgres://u:" + password + "@h\"\nquoted: '" + password + "'\nextra: {nested: {password: '" + password + "'}}\nport: \"" + port + "\"\n")
+	if err := New(&Config{EncryptionKey: key}).Load(&embeddedConfig, file.Name()); err != nil {
+		t.Fatalf("No error should happen when load embedded encrypted values, but got %v", err)
+	}
+
+	if embeddedConfig.URL != `postgres://u:p@ss: "word"@h` || embeddedConfig.Quoted != `p@ss: "word"` || embeddedConfig.Port != 5432 {
+		t.Errorf("encrypted values in larger and quoted values should be decrypted, got %+v", embeddedConfig)
+	}
+
+	if nested, _ := embeddedConfig.Extra["nested"].(map[string]interface{}); nested["password"] != `p@ss: "word"` {
+		t.Errorf("encrypted values in interface values should be decrypted, got %+v", embeddedConfig.Extra)
+	}
+
+	file.Truncate(0)
+	file.Seek(0, 0)
+	file.WriteString("port: " + strings.Replace(password, "type:str", "type:int", 1) + "\n")
+	if err := New(&Config{EncryptionKey: key}).Load(&encryptedConfig{}, file.Name()); err == nil || strings.Contains(err.Error(), "word") {
+		t.Errorf("should return error without the plaintext if decrypted value is not of its type, but got %v", err)
+	}
+}
+
+type testLogRecord struct {
+	level string
+	msg   string
+	args  []interface{}
+}
+
+type testLogger struct {
+	records []testLogRecord
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) { l.log("debug", msg, args) }
+func (l *testLogger) Info(msg string, args ...interface{})  { l.log("info", msg, args) }
+func (l *testLogger) Warn(msg string, args ...interface{})  { l.log("warn", msg, args) }
+func (l *testLogger) Error(msg string, args ...interface{}) { l.log("error", msg, args) }
+
+func (l *testLogger) log(level, msg string, args []interface{}) {
+	l.records = append(l.records, testLogRecord{level: level, msg: msg, args: args})
+}
+
+func (l *testLogger) find(level, msg string) *testLogRecord {
+	for _, record := range l.records {
+		if record.level == level && record.msg == msg {
+			return &record
+		}
+	}
+	return nil
+}
+
+func TestLogger(t *testing.T) {
+	type loggerConfig struct {
+		APPName string
+	}
+
+	file, err := ioutil.TempFile("/tmp", "configor.*.yml")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	file.WriteString("appname: configor\n")
+
+	os.Setenv("CONFIGOR_APPNAME", "env")
+	defer os.Unsetenv("CONFIGOR_APPNAME")
+
+	logger := &testLogger{}
+	if err := New(&Config{Logger: logger, Verbose: true, Environment: "production"}).Load(&loggerConfig{}, file.Name(), "missing.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if record := logger.find("info", "Current environment"); record == nil || !reflect.DeepEqual(record.args, []interface{}{"environment", "production"}) {
+		t.Errorf("should log current environment, but got %+v", record)
+	}
+
+	if record := logger.find("info", "Loading configurations from file"); record == nil || !reflect.DeepEqual(record.args, []interface{}{"file", file.Name()}) {
+		t.Errorf("should log loaded files, but got %+v", record)
+	}
+
+	if record := logger.find("warn", "Failed to find configuration"); record == nil || !reflect.DeepEqual(record.args, []interface{}{"file", "missing.yml"}) {
+		t.Errorf("should warn missing files, but got %+v", record)
+	}
+
+	if logger.find("debug", "Trying to load field from env") == nil || logger.find("info", "Loading configuration for field from env") == nil {
+		t.Errorf("should log env lookups in verbose mode, but got %+v", logger.records)
+	}
+
+	logger = &testLogger{}
+	if err := New(&Config{Logger: logger, Silent: true}).Load(&loggerConfig{}, file.Name(), "missing.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if len(logger.records) != 0 {
+		t.Errorf("should not log anything in silent mode, but got %+v", logger.records)
+	}
+}
+
+func TestEnvironmentDetectors(t *testing.T) {
+	markerFile, err := ioutil.TempFile("/tmp", "configor.*.env")
+	if err != nil {
+		t.Fatal("Could not create temp file")
+	}
+	defer os.Remove(markerFile.Name())
+	defer markerFile.Close()
+	markerFile.WriteString("staging\n")
+
+	os.Setenv("GO_ENV", "qa")
+	defer os.Unsetenv("GO_ENV")
+
+	if env := New(&Config{EnvironmentDetectors: []EnvironmentDetector{EnvVarDetector("APP_ENV", "GO_ENV")}}).GetEnvironment(); env != "qa" {
+		t.Errorf("should detect environment from custom env names, but got %v", env)
+	}
+
+	if env := New(&Config{EnvironmentDetectors: []EnvironmentDetector{EnvVarDetector("APP_ENV"), FileDetector(markerFile.Name())}}).GetEnvironment(); env != "staging" {
+		t.Errorf("should detect environment from marker file, but got %v", env)
+	}
+
+	if env := New(&Config{FS: test, EnvironmentDetectors: []EnvironmentDetector{FileDetector(markerFile.Name())}}).GetEnvironment(); env != "staging" {
+		t.Errorf("should read marker file from local file system even if FS is set, but got %v", env)
+	}
+
+	if env := New(&Config{EnvironmentDetectors: []EnvironmentDetector{HostnameDetector(regexp.MustCompile(".*"), "production")}}).GetEnvironment(); env != "production" {
+		t.Errorf("should detect environment from hostname, but got %v", env)
+	}
+
+	callback := func(configor *Configor) (string, bool) { return "", false }
+	if env := New(&Config{EnvironmentDetectors: []EnvironmentDetector{callback, HostnameDetector(regexp.MustCompile("^$"), "production")}}).GetEnvironment(); env != "development" {
+		t.Errorf("should use development if no detectors detect the environment, but got %v", env)
+	}
+
+	if env := New(&Config{}).GetEnvironment(); env != "test" {
+		t.Errorf("should detect test environment by default, but got %v", env)
+	}
+
+	if err := New(&Config{AllowedEnvironments: []string{"production", "staging"}}).Load(&struct{}{}); err == nil || !strings.Contains(err.Error(), "environment test is not allowed") {
+		t.Errorf("should return error if environment is not allowed, but got %v", err)
+	}
+
+	if err := New(&Config{Environment: "staging", AllowedEnvironments: []string{"production", "staging"}}).Load(&struct{}{}); err != nil {
+		t.Errorf("No error should happen for allowed environments, but got %v", err)
+	}
+}
+
+func TestEnvironmentInheritance(t *testing.T) {
+	type inheritanceConfig struct {
+		APPName string
+		Region  string
+		Debug   bool
+		Port    int
+	}
+
+	dir, err := ioutil.TempDir("/tmp", "configor")
+	if err != nil {
+		t.Fatal("Could not create temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(dir+"/config.yml", []byte("appname: configor\nport: 80\ndebug: true\n"), 0644)
+	ioutil.WriteFile(dir+"/config.production.yml", []byte("debug: false\nregion: us\nport: 443\n"), 0644)
+	ioutil.WriteFile(dir+"/config.production-eu.yml", []byte("region: eu\n"), 0644)
+
+	var result inheritanceConfig
+	if err := New(&Config{Environment: "production-eu", EnvironmentSeparator: "-"}).Load(&result, dir+"/config.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := (inheritanceConfig{APPName: "configor", Region: "eu", Debug: false, Port: 443}); result != expected {
+		t.Errorf("should inherit configurations of parent environments by naming convention, expected %+v, got %+v", expected, result)
+	}
+
+	result = inheritanceConfig{}
+	if err := New(&Config{Environment: "production-eu", EnvironmentParents: map[string]string{"production-eu": "production"}}).Load(&result, dir+"/config.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := (inheritanceConfig{APPName: "configor", Region: "eu", Debug: false, Port: 443}); result != expected {
+		t.Errorf("should inherit configurations of declared parent environments, expected %+v, got %+v", expected, result)
+	}
+
+	result = inheritanceConfig{}
+	if err := New(&Config{Environment: "production-eu"}).Load(&result, dir+"/config.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := (inheritanceConfig{APPName: "configor", Region: "eu", Debug: true, Port: 80}); result != expected {
+		t.Errorf("should not inherit configurations without parents, expected %+v, got %+v", expected, result)
+	}
+
+	err = New(&Config{Environment: "production-eu", EnvironmentParents: map[string]string{"production-eu": "production", "production": "production-eu"}}).Load(&inheritanceConfig{}, dir+"/config.yml")
+	if err == nil || !strings.Contains(err.Error(), "environment cycle") {
+		t.Errorf("should return error for environment cycles, but got %v", err)
+	}
+}
+
+func TestLocalAndHostOverrides(t *testing.T) {
+	type overrideConfig struct {
+		APPName string
+		Host    string
+		Port    int
+		Debug   bool
+	}
+
+	dir, err := ioutil.TempDir("/tmp", "configor")
+	if err != nil {
+		t.Fatal("Could not create temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(dir+"/config.yml", []byte("appname: configor\nhost: base.local\nport: 80\n"), 0644)
+	ioutil.WriteFile(dir+"/config.production.yml", []byte("host: production.local\nport: 443\n"), 0644)
+	ioutil.WriteFile(dir+"/config.web-1.yml", []byte("host: web-1.local\nport: 8443\n"), 0644)
+	ioutil.WriteFile(dir+"/config.local.yml", []byte("port: 3000\ndebug: true\n"), 0644)
+
+	var result overrideConfig
+	if err := New(&Config{Environment: "production", Hostname: "web-1", HostOverride: true, LocalOverride: true}).Load(&result, dir+"/config.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := (overrideConfig{APPName: "configor", Host: "web-1.local", Port: 3000, Debug: true}); result != expected {
+		t.Errorf("local override should overwrite host override, which overwrites environment variants, expected %+v, got %+v", expected, result)
+	}
+
+	result = overrideConfig{}
+	if err := New(&Config{Environment: "production", Hostname: "web-1"}).Load(&result, dir+"/config.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := (overrideConfig{APPName: "configor", Host: "production.local", Port: 443}); result != expected {
+		t.Errorf("overrides should not be loaded if not enabled, expected %+v, got %+v", expected, result)
+	}
+
+	os.Remove(dir + "/config.yml")
+	ioutil.WriteFile(dir+"/config.example.yml", []byte("appname: example\nhost: example.local\nport: 80\n"), 0644)
+
+	result = overrideConfig{}
+	if err := New(&Config{Environment: "development", LocalOverride: true, Silent: true}).Load(&result, dir+"/config.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := (overrideConfig{APPName: "example", Host: "example.local", Port: 3000, Debug: true}); result != expected {
+		t.Errorf("local override should overwrite the example file, expected %+v, got %+v", expected, result)
+	}
+
+	result = overrideConfig{}
+	logger := &testLogger{}
+	if err := New(&Config{Environment: "development", LocalOverride: true, ErrorOnMissingFiles: true, ExampleEnvironments: []string{}, Logger: logger}).Load(&result, dir+"/config.yml"); err != nil {
+		t.Errorf("a lone local override should count as found, but got %v", err)
+	}
+
+	if expected := (overrideConfig{Port: 3000, Debug: true}); result != expected || logger.find("warn", "Failed to find configuration") != nil {
+		t.Errorf("a lone local override should be loaded without warnings, expected %+v, got %+v", expected, result)
+	}
+
+	if env := New(&Config{Hostname: "web-1", EnvironmentDetectors: []EnvironmentDetector{HostnameDetector(regexp.MustCompile("^web-"), "production")}}).GetEnvironment(); env != "production" {
+		t.Errorf("should detect environment with Config.Hostname, but got %v", env)
+	}
+}
+
+func TestSearchPaths(t *testing.T) {
+	type searchConfig struct {
+		APPName string
+		Port    int
+	}
+
+	dir, err := ioutil.TempDir("/tmp", "configor")
+	if err != nil {
+		t.Fatal("Could not create temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(dir+"/home/app", 0755)
+	os.MkdirAll(dir+"/etc/app", 0755)
+	ioutil.WriteFile(dir+"/home/app/config.production.yml", []byte("port: 443\n"), 0644)
+	ioutil.WriteFile(dir+"/etc/app/config.yml", []byte("appname: etc\nport: 80\n"), 0644)
+	ioutil.WriteFile(dir+"/etc/app/database.yml", []byte("appname: database\n"), 0644)
+
+	os.Setenv("CONFIGOR_TEST_CONFIG_HOME", dir+"/home")
+	defer os.Unsetenv("CONFIGOR_TEST_CONFIG_HOME")
+
+	logger := &testLogger{}
+	searchPaths := []string{dir + "/missing", "$CONFIGOR_TEST_UNSET_HOME/app", "$CONFIGOR_TEST_CONFIG_HOME/app", dir + "/etc/app"}
+
+	var result searchConfig
+	if err := New(&Config{Environment: "production", SearchPaths: searchPaths, Debug: true, Logger: logger}).Load(&result, "config.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if expected := (searchConfig{Port: 443}); result != expected {
+		t.Errorf("should load files from the first search path containing environment variants, expected %+v, got %+v", expected, result)
+	}
+
+	var tried []interface{}
+	for _, record := range logger.records {
+		if record.msg == "Searching configuration file" {
+			tried = append(tried, record.args[1])
+		}
+	}
+	if expected := []interface{}{dir + "/missing/config.yml", dir + "/home/app/config.yml"}; !reflect.DeepEqual(tried, expected) {
+		t.Errorf("should log paths tried, expected %v, got %v", expected, tried)
+	}
+
+	result = searchConfig{}
+	if err := New(&Config{SearchPaths: searchPaths}).Load(&result, "database.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if result.APPName != "database" {
+		t.Errorf("should search files in all search paths, but got %+v", result)
+	}
+}
+
+func TestErrorOnMissingFiles(t *testing.T) {
+	type missingConfig struct {
+		APPName string
+	}
+
+	dir, err := ioutil.TempDir("/tmp", "configor")
+	if err != nil {
+		t.Fatal("Could not create temp dir")
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/config.yml", []byte("appname: configor\n"), 0644)
+
+	err = New(&Config{ErrorOnMissingFiles: true, Environment: "production"}).Load(&missingConfig{}, dir+"/config.yml", dir+"/confg.yml")
+	var missingFileError *MissingFileError
+	if !errors.As(err, &missingFileError) {
+		t.Fatalf("should return MissingFileError, but got %v", err)
+	}
+
+	if expected := []string{dir + "/confg.yml", dir + "/confg.production.yml", dir + "/confg.example.yml"}; missingFileError.File != dir+"/confg.yml" || !reflect.DeepEqual(missingFileError.Candidates, expected) {
+		t.Errorf("MissingFileError should list candidates %v, but got %+v", expected, missingFileError)
+	}
+
+	err = New(&Config{ErrorOnMissingFiles: true, SearchPaths: []string{dir + "/a", dir + "/b"}}).Load(&missingConfig{}, "config.yml")
+	if !errors.As(err, &missingFileError) || len(missingFileError.Candidates) != 6 || missingFileError.Candidates[3] != dir+"/b/config.yml" {
+		t.Errorf("MissingFileError should list candidates in all search paths, but got %v", err)
+	}
+
+	if err := New(&Config{ErrorOnMissingFiles: true}).Load(&missingConfig{}, dir+"/*.json"); !errors.As(err, &missingFileError) {
+		t.Errorf("should return MissingFileError if glob pattern matches nothing, but got %v", err)
+	}
+
+	var result missingConfig
+	if err := New(&Config{ErrorOnMissingFiles: true}).Load(&result, dir+"/config.yml", Optional(dir+"/config.local.yml")); err != nil || result.APPName != "configor" {
+		t.Errorf("should skip missing optional files, but got %+v, %v", result, err)
+	}
+
+	if err := New(&Config{}).Load(&missingConfig{}, dir+"/confg.yml"); err != nil {
+		t.Errorf("No error should happen for missing files by default, but got %v", err)
+	}
+
+	err = New(&Config{ErrorOnMissingFiles: true, Environment: "production", Hostname: "web-1", HostOverride: true, LocalOverride: true}).Load(&missingConfig{}, dir+"/confg.yml")
+	if expected := []string{dir + "/confg.yml", dir + "/confg.production.yml", dir + "/confg.example.yml", dir + "/confg.web-1.yml", dir + "/confg.local.yml"}; !errors.As(err, &missingFileError) || !reflect.DeepEqual(missingFileError.Candidates, expected) {
+		t.Errorf("MissingFileError should list override candidates %v, but got %v", expected, err)
+	}
+
+	logger := &testLogger{}
+	if err := New(&Config{ErrorOnMissingFiles: true, Verbose: true, Logger: logger}).Load(&missingConfig{}, Optional(dir+"/config.local.yml"), dir+"/confg.yml"); err == nil {
+		t.Errorf("should return MissingFileError, but got nil")
+	}
+
+	if record := logger.find("error", "Failed to load configuration"); record == nil || !reflect.DeepEqual(record.args[1], []string{dir + "/config.local.yml", dir + "/confg.yml"}) {
+		t.Errorf("should log files without optional markers, but got %+v", record)
+	}
+}
+
+func TestExampleFallbackControls(t *testing.T) {
+	type exampleConfig struct {
+		APPName string
+	}
+
+	dir, err := ioutil.TempDir("/tmp", "configor")
+	if err != nil {
+		t.Fatal("Could not create temp dir")
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/config.example.yml", []byte("appname: example\n"), 0644)
+	ioutil.WriteFile(dir+"/config.sample.yml", []byte("appname: sample\n"), 0644)
+
+	var result exampleConfig
+	if err := New(&Config{Environment: "development", ExampleEnvironments: []string{"development", "test"}}).Load(&result, dir+"/config.yml"); err != nil || result.APPName != "example" {
+		t.Errorf("should use example files in allowed environments, but got %+v, %v", result, err)
+	}
+
+	result = exampleConfig{}
+	if err := New(&Config{Environment: "production", ExampleEnvironments: []string{"development", "test"}}).Load(&result, dir+"/config.yml"); err != nil || result.APPName != "" {
+		t.Errorf("should not use example files in environments not allowed, but got %+v, %v", result, err)
+	}
+
+	err = New(&Config{Environment: "production", ExampleEnvironments: []string{}, ErrorOnMissingFiles: true}).Load(&exampleConfig{}, dir+"/config.yml")
+	var missingFileError *MissingFileError
+	if !errors.As(err, &missingFileError) || len(missingFileError.Candidates) != 2 {
+		t.Errorf("should return MissingFileError without example candidates if example files are disabled, but got %v", err)
+	}
+
+	result = exampleConfig{}
+	if err := New(&Config{ExampleSuffix: "sample"}).Load(&result, dir+"/config.yml"); err != nil || result.APPName != "sample" {
+		t.Errorf("should use example files with custom suffix, but got %+v, %v", result, err)
+	}
+
+	err = New(&Config{Environment: "staging", ErrorOnExampleFallback: true}).Load(&exampleConfig{}, dir+"/config.yml")
+	var exampleFallbackError *ExampleFallbackError
+	if !errors.As(err, &exampleFallbackError) || exampleFallbackError.Example != dir+"/config.example.yml" || exampleFallbackError.Environment != "staging" {
+		t.Errorf("should return ExampleFallbackError, but got %v", err)
+	}
+
+	logger := &testLogger{}
+	if err := New(&Config{Environment: "staging", Logger: logger}).Load(&exampleConfig{}, dir+"/config.yml"); err != nil {
+		t.Fatalf("No error should happen when load configurations, but got %v", err)
+	}
+
+	if record := logger.find("warn", "Failed to find configuration, using example file"); record == nil || !reflect.DeepEqual(record.args, []interface{}{"file", dir + "/config.yml", "example", dir + "/config.example.yml", "environment", "staging"}) {
+		t.Errorf("should warn when using example files, but got %+v", record)
+	}
+
+	confDir := dir + "/conf.d"
+	os.Mkdir(confDir, 0755)
+	ioutil.WriteFile(confDir+"/app.example.yml", []byte("appname: example\n"), 0644)
+
+	for _, pattern := range []string{confDir, confDir + "/*.yml"} {
+		result = exampleConfig{}
+		if err := New(&Config{Environment: "development", Silent: true}).Load(&result, pattern); err != nil || result.APPName != "example" {
+			t.Errorf("should use example files in %v, but got %+v, %v", pattern, result, err)
+		}
+
+		result = exampleConfig{}
+		if err := New(&Config{Environment: "production", ExampleEnvironments: []string{}, ErrorOnMissingFiles: true}).Load(&result, pattern); err != nil || result.APPName != "" {
+			t.Errorf("should not use example files in %v if example files are disabled, but got %+v, %v", pattern, result, err)
+		}
+
+		err = New(&Config{Environment: "staging", ErrorOnExampleFallback: true}).Load(&exampleConfig{}, pattern)
+		if !errors.As(err, &exampleFallbackError) || exampleFallbackError.Example != confDir+"/app.example.yml" {
+			t.Errorf("should return ExampleFallbackError for example files in %v, but got %v", pattern, err)
+		}
+	}
+
+	ioutil.WriteFile(confDir+"/app.yml", []byte("appname: app\n"), 0644)
+	result = exampleConfig{}
+	if err := New(&Config{Environment: "staging", ErrorOnExampleFallback: true}).Load(&result, confDir); err != nil || result.APPName != "app" {
+		t.Errorf("should not use example files in directories if base files exist, but got %+v, %v", result, err)
+	}
+}
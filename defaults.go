@@ -0,0 +1,74 @@
+package configor
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFunc computes the default value for fields tagged with `default:"func:name"`,
+// config is the configuration being loaded, so the value could be derived from other fields
+type DefaultFunc func(config interface{}) (interface{}, error)
+
+// isComputedDefault returns true if the default value references env variables, templates or default funcs
+func isComputedDefault(value string) bool {
+	return strings.HasPrefix(value, "func:") || strings.Contains(value, "${") || strings.Contains(value, "{{")
+}
+
+func (configor *Configor) computeDefault(root interface{}, value string) (interface{}, error) {
+	if strings.HasPrefix(value, "func:") {
+		name := strings.TrimPrefix(value, "func:")
+		fc, ok := configor.DefaultFuncs[name]
+		if !ok {
+			return nil, fmt.Errorf("default func %v is not registered", name)
+		}
+		return fc(root)
+	}
+
+	if strings.Contains(value, "{{") {
+		tmpl, err := template.New("default").Option("missingkey=error").Parse(value)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, root); err != nil {
+			return nil, err
+		}
+		value = buf.String()
+	}
+
+	return newInterpolator(configor, root).interpolate(value)
+}
+
+func (configor *Configor) setComputedDefault(root interface{}, field reflect.Value, value string) error {
+	result, err := configor.computeDefault(root, value)
+	if err != nil || result == nil {
+		return err
+	}
+
+	if str, ok := result.(string); ok {
+		if field.Kind() == reflect.String {
+			field.SetString(str)
+			return nil
+		}
+		return yaml.Unmarshal([]byte(str), field.Addr().Interface())
+	}
+
+	resultValue := reflect.ValueOf(result)
+	switch {
+	case resultValue.Type().AssignableTo(field.Type()):
+		field.Set(resultValue)
+	case field.Kind() == reflect.String:
+		field.SetString(fmt.Sprint(result))
+	case resultValue.Type().ConvertibleTo(field.Type()):
+		field.Set(resultValue.Convert(field.Type()))
+	default:
+		return fmt.Errorf("can't use %#v as %v", result, field.Type())
+	}
+	return nil
+}
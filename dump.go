@@ -0,0 +1,133 @@
+package configor
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	redactedValue = "******"
+	unsetValue    = "<unset>"
+)
+
+// Dump formats config like `%#v`, values of fields tagged with `secret:"true"` or `sensitive:"true"` are replaced
+// with `******`, or `<unset>` if they are blank, so it is safe to write the result to logs
+func Dump(config interface{}) string {
+	var builder strings.Builder
+	dumpValue(&builder, reflect.ValueOf(config))
+	return builder.String()
+}
+
+// isSensitiveField returns true if values of the field should be redacted
+func isSensitiveField(fieldStruct reflect.StructField) bool {
+	return fieldStruct.Tag.Get("secret") == "true" || fieldStruct.Tag.Get("sensitive") == "true"
+}
+
+// hasSensitiveFields returns true if values of type t might contain sensitive fields
+func hasSensitiveFields(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return hasSensitiveFields(t.Elem(), visited)
+	case reflect.Map:
+		return hasSensitiveFields(t.Key(), visited) || hasSensitiveFields(t.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if isSensitiveField(t.Field(i)) || hasSensitiveFields(t.Field(i).Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func dumpValue(builder *strings.Builder, v reflect.Value) {
+	if !v.IsValid() {
+		builder.WriteString("<nil>")
+		return
+	}
+
+	if !hasSensitiveFields(v.Type(), map[reflect.Type]bool{}) {
+		fmt.Fprintf(builder, "%#v", v)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			fmt.Fprintf(builder, "%#v", v)
+			return
+		}
+		dumpValue(builder, v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			fmt.Fprintf(builder, "%#v", v)
+			return
+		}
+		builder.WriteString("&")
+		dumpValue(builder, v.Elem())
+	case reflect.Struct:
+		builder.WriteString(v.Type().String() + "{")
+		for i := 0; i < v.NumField(); i++ {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+
+			fieldStruct := v.Type().Field(i)
+			builder.WriteString(fieldStruct.Name + ":")
+			if !isSensitiveField(fieldStruct) {
+				dumpValue(builder, v.Field(i))
+			} else if v.Field(i).IsZero() {
+				builder.WriteString(unsetValue)
+			} else {
+				builder.WriteString(redactedValue)
+			}
+		}
+		builder.WriteString("}")
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			fmt.Fprintf(builder, "%#v", v)
+			return
+		}
+
+		builder.WriteString(v.Type().String() + "{")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+			dumpValue(builder, v.Index(i))
+		}
+		builder.WriteString("}")
+	case reflect.Map:
+		if v.IsNil() {
+			fmt.Fprintf(builder, "%#v", v)
+			return
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+
+		builder.WriteString(v.Type().String() + "{")
+		for i, key := range keys {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+			dumpValue(builder, key)
+			builder.WriteString(":")
+			dumpValue(builder, v.MapIndex(key))
+		}
+		builder.WriteString("}")
+	default:
+		fmt.Fprintf(builder, "%#v", v)
+	}
+}
@@ -0,0 +1,487 @@
+package configor
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvNamer returns names of shell environments to load a field from, in priority order, names are the env prefix
+// and names of fields leading to the field, like `[]string{"Configor", "DB", "MaxOpenConns"}`
+type EnvNamer func(names []string) []string
+
+// DefaultEnvNamer joins names with `_`, and tries both the names and the upper case names,
+// like `Configor_DB_MaxOpenConns` and `CONFIGOR_DB_MAXOPENCONNS`
+func DefaultEnvNamer(names []string) []string {
+	name := strings.Join(names, "_")
+	return []string{name, strings.ToUpper(name)}
+}
+
+// SnakeCaseEnvNamer splits CamelCase names into upper case words joined with `_`, and joins names with separator,
+// like `CONFIGOR_DB_MAX_OPEN_CONNS` with separator `_`, or `CONFIGOR__DB__MAX_OPEN_CONNS` with separator `__`
+func SnakeCaseEnvNamer(separator string) EnvNamer {
+	return func(names []string) []string {
+		words := make([]string, len(names))
+		for i, name := range names {
+			words[i] = strings.ToUpper(toSnakeCase(name))
+		}
+		return []string{strings.Join(words, separator)}
+	}
+}
+
+// LowerCaseEnvNamer returns lower case names generated by namer, like `configor_db_max_open_conns`
+func LowerCaseEnvNamer(namer EnvNamer) EnvNamer {
+	return func(names []string) []string {
+		envNames := namer(names)
+		for i, name := range envNames {
+			envNames[i] = strings.ToLower(name)
+		}
+		return envNames
+	}
+}
+
+func (configor *Configor) lookupEnv(key string) (string, bool) {
+	if configor.Config.LookupEnv != nil {
+		return configor.Config.LookupEnv(key)
+	}
+	return os.LookupEnv(key)
+}
+
+func (configor *Configor) getenv(key string) string {
+	value, _ := configor.lookupEnv(key)
+	return value
+}
+
+// environ returns shell environments, process environments are not used if LookupEnv is set without Environ
+func (configor *Configor) environ() []string {
+	if configor.Config.Environ != nil {
+		return configor.Config.Environ()
+	} else if configor.Config.LookupEnv == nil {
+		return os.Environ()
+	}
+	return nil
+}
+
+// splitEnvNames splits env names of tags like `env:"DB_PASSWORD,DATABASE_PASSWORD"`
+func splitEnvNames(names string) []string {
+	var envNames []string
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			envNames = append(envNames, name)
+		}
+	}
+	return envNames
+}
+
+// lookupEnvValue returns the first env set in envNames and its value, empty values are ignored unless allowEmpty
+func (configor *Configor) lookupEnvValue(envNames []string, allowEmpty bool) (string, string, bool) {
+	for _, env := range envNames {
+		if value, ok := configor.lookupEnv(env); ok && (value != "" || allowEmpty) {
+			return env, value, true
+		}
+	}
+	return "", "", false
+}
+
+func (configor *Configor) getEnvNames(names []string) []string {
+	if configor.Config.EnvNamer != nil {
+		return configor.Config.EnvNamer(names)
+	}
+	return DefaultEnvNamer(names)
+}
+
+// toSnakeCase splits CamelCase name into words joined with `_`, like `APPName` to `APP_Name`
+func toSnakeCase(name string) string {
+	var (
+		runes  = []rune(name)
+		result []rune
+	)
+
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && runes[i-1] != '_' {
+			if prev := runes[i-1]; unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				result = append(result, '_')
+			}
+		}
+		result = append(result, r)
+	}
+	return string(result)
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	// yamlObsoleteUnmarshalerType is the yaml.v2 style unmarshaler, still supported by yaml.v3
+	yamlObsoleteUnmarshalerType = reflect.TypeOf((*interface {
+		UnmarshalYAML(unmarshal func(interface{}) error) error
+	})(nil)).Elem()
+)
+
+// decodeEnvValue decodes shell environment value into field, separator is used to split slices like `a,b,c`
+// and maps like `k=v,k2=v2`, values in yaml format are decoded with yaml
+func decodeEnvValue(field reflect.Value, value, separator string) error {
+	// empty values clear the field
+	if value == "" && field.Kind() != reflect.String {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return decodeEnvValue(field.Elem(), value, separator)
+	}
+
+	// types like time.Time, net.IP
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	// types implementing yaml unmarshalers, like levels accepting both names and numbers
+	if field.Kind() != reflect.String && field.CanAddr() && (field.Addr().Type().Implements(yamlUnmarshalerType) || field.Addr().Type().Implements(yamlObsoleteUnmarshalerType)) {
+		return yaml.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+
+	switch field.Type() {
+	case durationType:
+		duration, err := time.ParseDuration(value)
+		if err == nil {
+			field.SetInt(int64(duration))
+		}
+		return err
+	case urlType:
+		u, err := url.Parse(value)
+		if err == nil {
+			field.Set(reflect.ValueOf(*u))
+		}
+		return err
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "", "0", "f", "false":
+			field.SetBool(false)
+		default:
+			field.SetBool(true)
+		}
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(strings.TrimSpace(value), 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.TrimSpace(value), 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		switch trimmed := strings.TrimSpace(value); {
+		case field.Type().Elem().Kind() == reflect.Uint8:
+			field.SetBytes([]byte(value))
+		case !isScalarEnvType(field.Type().Elem()) || strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "- "):
+			// yaml sequences like `[a, b]` or `- a\n- b`
+			return yaml.Unmarshal([]byte(value), field.Addr().Interface())
+		default:
+			var values []string
+			if trimmed != "" {
+				values = strings.Split(value, separator)
+			}
+
+			slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+			for i, v := range values {
+				if err := decodeEnvValue(slice.Index(i), strings.TrimSpace(v), separator); err != nil {
+					return err
+				}
+			}
+			field.Set(slice)
+		}
+	case reflect.Map:
+		if !isScalarEnvType(field.Type().Key()) || !isScalarEnvType(field.Type().Elem()) || strings.HasPrefix(strings.TrimSpace(value), "{") || !strings.Contains(value, "=") {
+			// yaml mappings like `{k: v}` or `k: v`
+			return yaml.Unmarshal([]byte(value), field.Addr().Interface())
+		}
+
+		m := reflect.MakeMap(field.Type())
+		for _, pair := range strings.Split(value, separator) {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid map entry %v, should be key=value", pair)
+			}
+
+			key, elem := reflect.New(field.Type().Key()).Elem(), reflect.New(field.Type().Elem()).Elem()
+			if err := decodeEnvValue(key, strings.TrimSpace(kv[0]), separator); err != nil {
+				return err
+			}
+			if err := decodeEnvValue(elem, strings.TrimSpace(kv[1]), separator); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		field.Set(m)
+	default:
+		return yaml.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+	return nil
+}
+
+// isScalarEnvType returns true if values of type t could be decoded from a single shell environment value
+func isScalarEnvType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == durationType || t == urlType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// envNameSentinel is used as a placeholder of map keys to match env names generated by EnvNamer
+const envNameSentinel = "\x00"
+
+// processMapTags loads map values from shell environments, map keys are part of env names,
+// like `CONFIGOR_UPSTREAMS_BILLING_TIMEOUT` for `Upstreams["billing"].Timeout`, new keys found in
+// shell environments are added to the map with lower case names
+func (configor *Configor) processMapTags(field reflect.Value, path []string, prefixes []string) error {
+	var (
+		mapType  = field.Type()
+		elemType = mapType.Elem()
+		isStruct = !isScalarEnvType(elemType) && isStructType(elemType)
+	)
+
+	if !isScalarEnvType(mapType.Key()) || !isStruct && !isScalarEnvType(elemType) {
+		return nil
+	}
+
+	existingKeys := map[string]bool{}
+	for _, key := range field.MapKeys() {
+		keyName := fmt.Sprint(key.Interface())
+		existingKeys[strings.ToLower(keyName)] = true
+
+		elem := reflect.New(elemType).Elem()
+		elem.Set(field.MapIndex(key))
+		if loaded, err := configor.processMapElemTags(elem, append(path, keyName), append(prefixes, keyName), isStruct); err != nil {
+			return err
+		} else if loaded {
+			field.SetMapIndex(key, elem)
+		}
+	}
+
+	for _, keyName := range configor.lookupEnvKeys(elemType, prefixes, isStruct) {
+		if existingKeys[keyName] {
+			continue
+		}
+		existingKeys[keyName] = true
+
+		// skip envs with keys of other types, like `CONFIGOR_PORTS_ABC` for `Ports map[int]string`, same as slice indexes
+		key, elem := reflect.New(mapType.Key()).Elem(), reflect.New(elemType).Elem()
+		if err := decodeEnvValue(key, keyName, ","); err != nil {
+			continue
+		}
+
+		if isStruct {
+			if elem.Kind() == reflect.Ptr {
+				elem.Set(reflect.New(elemType.Elem()))
+			}
+			elemPtr := reflect.Indirect(elem).Addr().Interface()
+			if err := configor.processDefaults(elemPtr, elemPtr, append(path, keyName), false); err != nil {
+				return err
+			}
+		}
+
+		if _, err := configor.processMapElemTags(elem, append(path, keyName), append(prefixes, keyName), isStruct); err != nil {
+			return err
+		}
+
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(mapType))
+		}
+		field.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+// processSliceTags loads elements of slices of structs from shell environments, slice indexes are part of env names,
+// like `CONFIGOR_CONTACTS_0_NAME`, elements with indexes beyond the slice's length are appended in ascending order,
+// so indexes could be sparse
+func (configor *Configor) processSliceTags(field reflect.Value, path []string, prefixes []string) error {
+	for i := 0; i < field.Len(); i++ {
+		if elem := field.Index(i); elem.Kind() != reflect.Ptr || !elem.IsNil() {
+			if err := configor.processTags(reflect.Indirect(elem).Addr().Interface(), append(path, fmt.Sprint(i)), append(prefixes, fmt.Sprint(i))...); err != nil {
+				return err
+			}
+		}
+	}
+
+	var indexes []int
+	for _, key := range configor.lookupEnvKeys(field.Type().Elem(), prefixes, true) {
+		if idx, err := strconv.Atoi(key); err == nil && idx >= field.Len() {
+			indexes = append(indexes, idx)
+		}
+	}
+	sort.Ints(indexes)
+
+	for i, idx := range indexes {
+		if i > 0 && indexes[i-1] == idx {
+			continue
+		}
+
+		elem := reflect.New(field.Type().Elem()).Elem()
+		if elem.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+
+		elemPtr := reflect.Indirect(elem).Addr().Interface()
+		if err := configor.processDefaults(elemPtr, elemPtr, append(path, fmt.Sprint(field.Len())), false); err != nil {
+			return err
+		}
+
+		if err := configor.processTags(elemPtr, append(path, fmt.Sprint(field.Len())), append(prefixes, fmt.Sprint(idx))...); err != nil {
+			return err
+		}
+		field.Set(reflect.Append(field, elem))
+	}
+	return nil
+}
+
+// processMapElemTags loads map value elem from shell environments, returns true if elem should be set back to the map
+func (configor *Configor) processMapElemTags(elem reflect.Value, path []string, prefixes []string, isStruct bool) (bool, error) {
+	if isStruct {
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				return false, nil
+			}
+			return false, configor.processTags(elem.Interface(), path, prefixes...)
+		}
+		return true, configor.processTags(elem.Addr().Interface(), path, prefixes...)
+	}
+
+	envNames := configor.getEnvNames(prefixes)
+	if configor.Config.Verbose {
+		configor.logger().Debug("Trying to load map value from env", "key", prefixes[len(prefixes)-1], "env", strings.Join(envNames, ", "))
+	}
+
+	if env, value, ok := configor.lookupEnvValue(envNames, configor.Config.AllowEmptyEnv); ok {
+		if configor.Config.Debug || configor.Config.Verbose {
+			configor.logger().Info("Loading configuration for map value from env", "key", prefixes[len(prefixes)-1], "env", env)
+		}
+		if err := decodeEnvValue(elem, value, ","); err != nil {
+			return false, fmt.Errorf("failed to load map value %v from env %v, got %v", prefixes[len(prefixes)-1], env, err)
+		}
+		configor.recordOrigin(path, Origin{Kind: OriginEnv, Env: env})
+		return true, nil
+	}
+	return false, nil
+}
+
+// lookupEnvKeys returns lower case map keys or slice indexes found in names of shell environments
+func (configor *Configor) lookupEnvKeys(elemType reflect.Type, prefixes []string, isStruct bool) []string {
+	var (
+		keys      []string
+		leafPaths = [][]string{nil}
+	)
+
+	if isStruct {
+		leafPaths = getEnvLeafPaths(elemType, map[reflect.Type]bool{})
+	}
+
+	for _, env := range configor.environ() {
+		var (
+			name      = strings.SplitN(env, "=", 2)[0]
+			key       string
+			suffixLen = -1
+		)
+
+		// an env name might match several leaf paths, like `X_DB_HOST` matches key `x` with leaf `DB.Host`, and key
+		// `x_db` with leaf `Host`, prefer the longest leaf path, as keys are less likely to contain separators
+		for _, leafPath := range leafPaths {
+			for _, envName := range configor.getEnvNames(append(append(append([]string{}, prefixes...), envNameSentinel), leafPath...)) {
+				if parts := strings.SplitN(envName, envNameSentinel, 2); len(parts) == 2 && len(parts[1]) > suffixLen &&
+					len(name) > len(parts[0])+len(parts[1]) && strings.HasPrefix(name, parts[0]) && strings.HasSuffix(name, parts[1]) {
+					key, suffixLen = strings.ToLower(name[len(parts[0]):len(name)-len(parts[1])]), len(parts[1])
+				}
+			}
+		}
+
+		if suffixLen >= 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// getEnvLeafPaths returns the field names leading to scalar fields of struct type t, used in env names
+func getEnvLeafPaths(t reflect.Type, visited map[reflect.Type]bool) [][]string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if visited[t] {
+		return nil
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	var paths [][]string
+	for i := 0; i < t.NumField(); i++ {
+		fieldStruct := t.Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		// fields with env tag are loaded from fixed env names
+		if fieldStruct.Tag.Get("env") != "" {
+			continue
+		}
+
+		prefixes := getPrefixForStruct(nil, &fieldStruct)
+		if isScalarEnvType(fieldStruct.Type) || fieldStruct.Type.Kind() == reflect.Slice || fieldStruct.Type.Kind() == reflect.Map {
+			paths = append(paths, prefixes)
+		} else if isStructType(fieldStruct.Type) {
+			for _, path := range getEnvLeafPaths(fieldStruct.Type, visited) {
+				paths = append(paths, append(append([]string{}, prefixes...), path...))
+			}
+		}
+	}
+	return paths
+}
+
+func isStructType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
@@ -0,0 +1,164 @@
+package configor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// EnvironmentDetector detects the current environment, returns false if it can't tell,
+// detectors in Config.EnvironmentDetectors are tried in order until one of them detects the environment
+type EnvironmentDetector func(configor *Configor) (string, bool)
+
+// DefaultEnvironmentDetectors returns detectors used if Config.EnvironmentDetectors is not set,
+// which detect the environment from env CONFIGOR_ENV, then `test` if running tests
+func DefaultEnvironmentDetectors() []EnvironmentDetector {
+	return []EnvironmentDetector{EnvVarDetector("CONFIGOR_ENV"), TestDetector()}
+}
+
+// EnvVarDetector detects the environment from the first set shell environment of names, like `APP_ENV`, `GO_ENV`
+func EnvVarDetector(names ...string) EnvironmentDetector {
+	return func(configor *Configor) (string, bool) {
+		for _, name := range names {
+			if env := configor.getenv(name); env != "" {
+				return env, true
+			}
+		}
+		return "", false
+	}
+}
+
+// HostnameDetector detects environment if the hostname matches hostnameRegexp
+func HostnameDetector(hostnameRegexp *regexp.Regexp, environment string) EnvironmentDetector {
+	return func(configor *Configor) (string, bool) {
+		if hostname := configor.getHostname(); hostname != "" && hostnameRegexp.MatchString(hostname) {
+			return environment, true
+		}
+		return "", false
+	}
+}
+
+// FileDetector detects the environment from content of the marker file, like `/etc/app/environment`, the file is
+// read from the local file system even if Config.FS is set, as marker files belong to the host, not the config bundle
+func FileDetector(file string) EnvironmentDetector {
+	return func(configor *Configor) (string, bool) {
+		if data, err := ioutil.ReadFile(file); err == nil {
+			if env := strings.TrimSpace(string(data)); env != "" {
+				return env, true
+			}
+		}
+		return "", false
+	}
+}
+
+// TestDetector detects the environment `test` if running tests
+func TestDetector() EnvironmentDetector {
+	return func(configor *Configor) (string, bool) {
+		if testRegexp.MatchString(os.Args[0]) {
+			return "test", true
+		}
+		return "", false
+	}
+}
+
+// validateEnvironment returns error if the current environment is not in Config.AllowedEnvironments
+func (configor *Configor) validateEnvironment() error {
+	if len(configor.Config.AllowedEnvironments) == 0 {
+		return nil
+	}
+
+	env := configor.GetEnvironment()
+	for _, allowed := range configor.Config.AllowedEnvironments {
+		if env == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("environment %v is not allowed, should be one of %v", env, strings.Join(configor.Config.AllowedEnvironments, ", "))
+}
+
+// getEnvironmentChain returns the current environment and environments it inherits from, parents first,
+// parents are looked up from Config.EnvironmentParents, then by trimming the last part after Config.EnvironmentSeparator
+func (configor *Configor) getEnvironmentChain() ([]string, error) {
+	chain := []string{configor.GetEnvironment()}
+	for {
+		env := chain[0]
+		parent, ok := configor.Config.EnvironmentParents[env]
+		if !ok && configor.Config.EnvironmentSeparator != "" {
+			if idx := strings.LastIndex(env, configor.Config.EnvironmentSeparator); idx > 0 {
+				parent, ok = env[:idx], true
+			}
+		}
+
+		if !ok || parent == "" {
+			return chain, nil
+		}
+
+		for _, e := range chain {
+			if e == parent {
+				return nil, fmt.Errorf("environment cycle %v -> %v", parent, strings.Join(chain, " -> "))
+			}
+		}
+		chain = append([]string{parent}, chain...)
+	}
+}
+
+// getHostname returns Config.Hostname, or the hostname reported by the kernel
+func (configor *Configor) getHostname() string {
+	if configor.Config.Hostname != "" {
+		return configor.Config.Hostname
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// appendEnvironmentFiles appends environment variants of file to the loading list, like `config.production.yml`,
+// `config.production-eu.yml` in the order of the environment chain, later files overwrite values of earlier files,
+// returns true if any environment variant is found
+func (configor *Configor) appendEnvironmentFiles(resultKeys *[]string, results map[string]time.Time, file string, including []string, includeKey string) (bool, error) {
+	chain, err := configor.getEnvironmentChain()
+	if err != nil {
+		return false, err
+	}
+
+	var found bool
+	for _, env := range chain {
+		if envFile, modTime, err := configor.getConfigurationFileWithENVPrefix(file, env); err == nil {
+			found = true
+			if err := configor.appendConfigurationFile(resultKeys, results, envFile, modTime, including, includeKey); err != nil {
+				return found, err
+			}
+		}
+	}
+	return found, nil
+}
+
+// appendOverrideFiles appends overrides of file to the loading list, the host override `config.<hostname>.yml` if
+// Config.HostOverride is set, then the local override `config.local.yml` if Config.LocalOverride is set, they should
+// be appended after all other variants of file, including the example file, returns true if any override is found
+func (configor *Configor) appendOverrideFiles(resultKeys *[]string, results map[string]time.Time, file string, including []string, includeKey string) (bool, error) {
+	var found bool
+	for _, override := range configor.getOverrides() {
+		if overrideFile, modTime, err := configor.getConfigurationFileWithENVPrefix(file, override); err == nil {
+			found = true
+			if err := configor.appendConfigurationFile(resultKeys, results, overrideFile, modTime, including, includeKey); err != nil {
+				return found, err
+			}
+		}
+	}
+	return found, nil
+}
+
+// getOverrides returns names of enabled overrides, the hostname if Config.HostOverride is set, then `local` if
+// Config.LocalOverride is set
+func (configor *Configor) getOverrides() (overrides []string) {
+	if hostname := configor.getHostname(); configor.Config.HostOverride && hostname != "" {
+		overrides = append(overrides, hostname)
+	}
+	if configor.Config.LocalOverride {
+		overrides = append(overrides, "local")
+	}
+	return overrides
+}
@@ -0,0 +1,201 @@
+package configor
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	flagBindings      = map[interface{}][]*flagValue{}
+	flagBindingsMutex sync.Mutex
+)
+
+// flagValue is a flag bound to a config field, its value is applied to the field when loading the config
+type flagValue struct {
+	owner     interface{}
+	index     []int
+	name      string
+	path      string
+	fieldType reflect.Type
+	separator string
+	value     string
+	set       bool
+	sensitive bool
+}
+
+// String returns the value of the flag, values of sensitive fields are redacted, so they are not printed as defaults
+func (f *flagValue) String() string {
+	if f.sensitive && f.value != "" {
+		return redactedValue
+	}
+	return f.value
+}
+
+func (f *flagValue) Set(value string) error {
+	if err := decodeEnvValue(reflect.New(f.fieldType).Elem(), value, f.separator); err != nil {
+		return err
+	}
+	f.value, f.set = value, true
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	if f.fieldType == nil {
+		return false
+	}
+
+	fieldType := f.fieldType
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	return fieldType.Kind() == reflect.Bool
+}
+
+// BindFlags defines flags for fields of config in fs, flag names are generated from field names like env names,
+// like `--db-name` for `DB.Name`, use tag `flag:"name"` to change the name or `flag:"-"` to skip the field, and tag
+// `usage:"..."` to set the usage, flags set when parsing fs are applied with the highest precedence when loading config.
+// Bindings are kept in a package registry keyed by config until UnbindFlags is called, unless the loading Configor
+// has Config.FlagSet, then bindings are looked up from the flag set
+func BindFlags(fs *flag.FlagSet, config interface{}) error {
+	configValue := reflect.ValueOf(config)
+	if configValue.Kind() != reflect.Ptr || configValue.Elem().Kind() != reflect.Struct {
+		return errors.New("invalid config, should be pointer of struct")
+	}
+
+	flags := bindFlags(fs, configValue.Elem(), nil, nil, map[reflect.Type]bool{})
+	for _, f := range flags {
+		f.owner = config
+	}
+
+	flagBindingsMutex.Lock()
+	defer flagBindingsMutex.Unlock()
+	flagBindings[config] = append(flagBindings[config], flags...)
+	return nil
+}
+
+func bindFlags(fs *flag.FlagSet, value reflect.Value, index []int, names []string, visited map[reflect.Type]bool) (flags []*flagValue) {
+	if visited[value.Type()] {
+		return nil
+	}
+	visited[value.Type()] = true
+	defer delete(visited, value.Type())
+
+	for i := 0; i < value.NumField(); i++ {
+		var (
+			fieldStruct = value.Type().Field(i)
+			field       = value.Field(i)
+			fieldIndex  = append(append([]int{}, index...), i)
+			fieldNames  = getPrefixForStruct(append([]string{}, names...), &fieldStruct)
+			flagName    = fieldStruct.Tag.Get("flag")
+		)
+
+		if !field.CanInterface() || flagName == "-" {
+			continue
+		}
+
+		if !isScalarEnvType(fieldStruct.Type) && isStructType(fieldStruct.Type) {
+			for field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					field = reflect.New(field.Type().Elem())
+				}
+				field = field.Elem()
+			}
+			flags = append(flags, bindFlags(fs, field, fieldIndex, fieldNames, visited)...)
+			continue
+		}
+
+		if fieldStruct.Type.Kind() == reflect.Slice && !isScalarEnvType(fieldStruct.Type.Elem()) ||
+			fieldStruct.Type.Kind() == reflect.Map && (!isScalarEnvType(fieldStruct.Type.Key()) || !isScalarEnvType(fieldStruct.Type.Elem())) {
+			continue
+		}
+
+		if flagName == "" {
+			flagName = toFlagName(fieldNames)
+		}
+
+		usage := fieldStruct.Tag.Get("usage")
+		if usage == "" {
+			usage = fmt.Sprintf("set %v", strings.Join(fieldNames, "."))
+		}
+
+		separator := fieldStruct.Tag.Get("env_separator")
+		if separator == "" {
+			separator = ","
+		}
+
+		f := &flagValue{index: fieldIndex, name: flagName, path: strings.Join(fieldNames, "."), fieldType: fieldStruct.Type, separator: separator, sensitive: isSensitiveField(fieldStruct)}
+		if !field.IsZero() {
+			f.value = fmt.Sprint(reflect.Indirect(field).Interface())
+		}
+		fs.Var(f, flagName, usage)
+		flags = append(flags, f)
+	}
+	return flags
+}
+
+// toFlagName returns lower case words of names joined with `-`, like `db-max-open-conns` for `DB.MaxOpenConns`
+func toFlagName(names []string) string {
+	words := make([]string, len(names))
+	for i, name := range names {
+		words[i] = strings.ToLower(strings.Replace(toSnakeCase(name), "_", "-", -1))
+	}
+	return strings.Join(words, "-")
+}
+
+// UnbindFlags removes flags bound to config from the package registry, flags stay defined in their flag sets,
+// but are no longer applied when loading config without Config.FlagSet
+func UnbindFlags(config interface{}) {
+	flagBindingsMutex.Lock()
+	defer flagBindingsMutex.Unlock()
+	delete(flagBindings, config)
+}
+
+// getFlagBindings returns flags bound to config in Config.FlagSet if set, or in the package registry
+func (configor *Configor) getFlagBindings(config interface{}) (flags []*flagValue) {
+	if fs := configor.Config.FlagSet; fs != nil {
+		fs.VisitAll(func(f *flag.Flag) {
+			if value, ok := f.Value.(*flagValue); ok && value.owner == config {
+				flags = append(flags, value)
+			}
+		})
+		return flags
+	}
+
+	flagBindingsMutex.Lock()
+	defer flagBindingsMutex.Unlock()
+	return flagBindings[config]
+}
+
+// processFlags applies values of flags set in command line to config
+func (configor *Configor) processFlags(config interface{}, flags []*flagValue) error {
+	for _, f := range flags {
+		if !f.set {
+			continue
+		}
+
+		field := reflect.ValueOf(config)
+		for _, i := range f.index {
+			for field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+				field = field.Elem()
+			}
+			field = field.Field(i)
+		}
+
+		if configor.Config.Debug || configor.Config.Verbose {
+			configor.logger().Info("Loading configuration for field from flag", "field", f.path, "flag", f.name)
+		}
+
+		if err := decodeEnvValue(field, f.value, f.separator); err != nil {
+			return fmt.Errorf("failed to load flag --%v, got %v", f.name, err)
+		}
+		configor.recordOrigin(strings.Split(f.path, "."), Origin{Kind: OriginFlag, Flag: f.name})
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package configor
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultIncludeKey is the default top level key of yaml and json configuration files to include other files
+const defaultIncludeKey = "include"
+
+var tomlImportRegexp = regexp.MustCompile(`(?m)^[ \t]*@import[ \t]+["']([^"']+)["'][ \t]*$`)
+
+// getIncludeKey returns the key of include directives for configurations of config's type, returns blank if include
+// directives are disabled by Config.IncludeKey, or the struct has a field mapping to the key, as its value is a configuration
+func (configor *Configor) getIncludeKey(config interface{}) string {
+	key := configor.Config.IncludeKey
+	if key == "" {
+		key = defaultIncludeKey
+	} else if key == "-" {
+		return ""
+	}
+
+	if hasFieldWithKey(reflect.Indirect(reflect.ValueOf(config)).Type(), key) {
+		return ""
+	}
+	return key
+}
+
+// hasFieldWithKey returns true if any field of struct t maps to key in yaml, json or toml files
+func hasFieldWithKey(t reflect.Type, key string) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		fieldStruct := t.Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		// fields of embedded structs are promoted in json files, and in yaml files if inlined
+		yamlKey, inline := getYAMLKey(fieldStruct)
+		if (inline || fieldStruct.Anonymous) && hasFieldWithKey(reflect.Indirect(reflect.New(fieldStruct.Type)).Type(), key) {
+			return true
+		}
+
+		if !inline && (strings.EqualFold(yamlKey, key) || strings.EqualFold(fieldStruct.Name, key)) {
+			return true
+		}
+
+		for _, tag := range []string{"json", "toml"} {
+			if name := strings.Split(fieldStruct.Tag.Get(tag), ",")[0]; strings.EqualFold(name, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// configurationFile is a read configuration file, cached by Configor until its modification time or size changes,
+// so files are read and decrypted once, and shared by include discovery, decoding and origin recording
+type configurationFile struct {
+	modTime    time.Time
+	size       int64
+	includeKey string
+	// raw is the original content, used to look up lines of keys
+	raw []byte
+	// data is the decrypted content without include directives
+	data     []byte
+	includes []string
+
+	keys     map[string]int
+	keysOnce sync.Once
+}
+
+// readConfigurationFile reads file, returns its decrypted data without include directives, and the files it includes,
+// include directives of yaml and json files are only extracted if includeKey is not blank
+func (configor *Configor) readConfigurationFile(file, includeKey string) (*configurationFile, error) {
+	fileInfo, err := configor.stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	configor.fileCacheMutex.Lock()
+	cached, ok := configor.fileCache[file]
+	configor.fileCacheMutex.Unlock()
+	if ok && cached.modTime.Equal(fileInfo.ModTime()) && cached.size == fileInfo.Size() && cached.includeKey == includeKey {
+		return cached, nil
+	}
+
+	raw, err := configor.readFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := configor.decryptConfigurationData(file, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var includes []string
+	switch {
+	case strings.HasSuffix(file, ".yaml") || strings.HasSuffix(file, ".yml"):
+		data, includes, err = extractYAMLIncludes(data, includeKey)
+	case strings.HasSuffix(file, ".toml"):
+		data, includes, err = extractTomlImports(data)
+	case strings.HasSuffix(file, ".json"):
+		data, includes, err = extractJSONIncludes(data, includeKey)
+	default:
+		if tomlData, tomlIncludes, tomlErr := extractTomlImports(data); len(tomlIncludes) > 0 {
+			data, includes, err = tomlData, tomlIncludes, tomlErr
+		} else {
+			// json files are valid yaml files
+			data, includes, err = extractYAMLIncludes(data, includeKey)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cached = &configurationFile{modTime: fileInfo.ModTime(), size: fileInfo.Size(), includeKey: includeKey, raw: raw, data: data, includes: includes}
+	configor.fileCacheMutex.Lock()
+	if configor.fileCache == nil {
+		configor.fileCache = map[string]*configurationFile{}
+	}
+	configor.fileCache[file] = cached
+	configor.fileCacheMutex.Unlock()
+	return cached, nil
+}
+
+func extractYAMLIncludes(data []byte, includeKey string) ([]byte, []string, error) {
+	if includeKey == "" {
+		return data, nil, nil
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return data, nil, nil
+	}
+
+	mapping := document.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == includeKey {
+			var includes []string
+			if value := mapping.Content[i+1]; value.Kind == yaml.ScalarNode {
+				includes = append(includes, value.Value)
+			} else if err := value.Decode(&includes); err != nil {
+				return nil, nil, fmt.Errorf("invalid %v, should be a file or a list of files: %v", includeKey, err)
+			}
+
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			data, err := yaml.Marshal(&document)
+			return data, includes, err
+		}
+	}
+	return data, nil, nil
+}
+
+func extractJSONIncludes(data []byte, includeKey string) ([]byte, []string, error) {
+	if includeKey == "" {
+		return data, nil, nil
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return data, nil, nil
+	}
+
+	value, ok := values[includeKey]
+	if !ok {
+		return data, nil, nil
+	}
+
+	var includes []string
+	if err := json.Unmarshal(value, &includes); err != nil {
+		var include string
+		if err := json.Unmarshal(value, &include); err != nil {
+			return nil, nil, fmt.Errorf("invalid %v, should be a file or a list of files: %v", includeKey, err)
+		}
+		includes = append(includes, include)
+	}
+
+	delete(values, includeKey)
+	data, err := json.Marshal(values)
+	return data, includes, err
+}
+
+func extractTomlImports(data []byte) ([]byte, []string, error) {
+	var includes []string
+	for _, match := range tomlImportRegexp.FindAllSubmatch(data, -1) {
+		includes = append(includes, string(match[1]))
+	}
+
+	if len(includes) == 0 {
+		return data, nil, nil
+	}
+	return tomlImportRegexp.ReplaceAll(data, nil), includes, nil
+}
+
+// resolveInclude returns the files matching include, relative paths are resolved against the directory of file,
+// glob patterns and directories are expanded like files passed to Load, returns true if include is expanded
+func (configor *Configor) resolveInclude(file, include string) ([]string, bool, error) {
+	if configor.FS != nil {
+		include = path.Join(path.Dir(file), include)
+	} else if !filepath.IsAbs(include) {
+		include = filepath.Join(filepath.Dir(file), include)
+	}
+
+	if files, expanded, err := configor.expandConfigurationFiles(include); expanded {
+		return files, true, err
+	}
+	return []string{include}, false, nil
+}
+
+// appendConfigurationFile appends file to the loading list after the files it includes, so its values
+// overwrite included values, including is the chain of files including it, used to detect include cycles
+func (configor *Configor) appendConfigurationFile(resultKeys *[]string, results map[string]time.Time, file string, modTime time.Time, including []string, includeKey string) error {
+	for _, f := range including {
+		if f == file {
+			return fmt.Errorf("include cycle %v -> %v", strings.Join(including, " -> "), file)
+		}
+	}
+
+	if _, ok := results[file]; ok {
+		return nil
+	}
+
+	configurationFile, err := configor.readConfigurationFile(file, includeKey)
+	if err != nil {
+		return err
+	}
+
+	including = append(including, file)
+	for _, include := range configurationFile.includes {
+		matches, expanded, err := configor.resolveInclude(file, include)
+		if err != nil {
+			return fmt.Errorf("failed to include %v from %v: %v", include, file, err)
+		}
+
+		if len(matches) == 0 && !configor.Silent {
+			configor.logger().Warn("Failed to find included configuration", "include", include, "from", file)
+		}
+
+		for _, match := range matches {
+			// base files of expanded variants might not exist
+			if fileInfo, err := configor.stat(match); err != nil && !expanded {
+				return fmt.Errorf("failed to include %v from %v: %v", include, file, err)
+			} else if err == nil {
+				if !fileInfo.Mode().IsRegular() {
+					continue
+				}
+
+				if configor.Config.Debug || configor.Config.Verbose {
+					configor.logger().Info("Including configuration file", "file", match, "from", file)
+				}
+
+				if err := configor.appendConfigurationFile(resultKeys, results, match, fileInfo.ModTime(), including, includeKey); err != nil {
+					return err
+				}
+			}
+
+			if _, err := configor.appendEnvironmentFiles(resultKeys, results, match, including, includeKey); err != nil {
+				return err
+			}
+
+			if _, err := configor.appendOverrideFiles(resultKeys, results, match, including, includeKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	*resultKeys = append(*resultKeys, file)
+	results[file] = modTime
+	return nil
+}
@@ -0,0 +1,263 @@
+package configor
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// InterpolationError errors are returned by the Load function when a `${...}` reference
+// in the configuration can't be resolved, File and Key are the file and key of the value
+// containing the reference.
+type InterpolationError struct {
+	File      string
+	Key       string
+	Reference string
+	Err       error
+}
+
+func (e *InterpolationError) Error() string {
+	msg := fmt.Sprintf("failed to resolve reference %v", e.Reference)
+	if e.Key != "" {
+		msg += fmt.Sprintf(" of key %v", e.Key)
+	}
+	if e.File != "" {
+		msg += fmt.Sprintf(" in file %v", e.File)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+var interpolationRegexp = regexp.MustCompile(`\$?\$\{([^{}]*)\}`)
+
+// interpolator resolves `${ENV_VAR}`, `${ENV_VAR:-fallback}` and `${self.DB.Host}` references,
+// `$${` could be used to escape a literal `${`
+type interpolator struct {
+	configor  *Configor
+	root      reflect.Value
+	resolved  map[string]string
+	resolving []string
+}
+
+func newInterpolator(configor *Configor, config interface{}) *interpolator {
+	return &interpolator{configor: configor, root: reflect.Indirect(reflect.ValueOf(config)), resolved: map[string]string{}}
+}
+
+func (i *interpolator) interpolate(value string) (string, error) {
+	var err error
+	result := interpolationRegexp.ReplaceAllStringFunc(value, func(reference string) string {
+		if err != nil {
+			return reference
+		}
+
+		if strings.HasPrefix(reference, "$$") {
+			return reference[1:]
+		}
+
+		resolved, resolveErr := i.resolve(reference[2 : len(reference)-1])
+		if resolveErr != nil {
+			err = &InterpolationError{Reference: reference, Err: resolveErr}
+		}
+		return resolved
+	})
+	return result, err
+}
+
+func (i *interpolator) resolve(expr string) (string, error) {
+	if strings.HasPrefix(expr, "self.") {
+		return i.resolveKey(strings.TrimPrefix(expr, "self."))
+	}
+
+	name, fallback, hasFallback := expr, "", false
+	if idx := strings.Index(expr, ":-"); idx >= 0 {
+		name, fallback, hasFallback = expr[:idx], expr[idx+2:], true
+	}
+
+	if value, ok := i.configor.lookupEnv(name); ok && (value != "" || !hasFallback) {
+		return value, nil
+	} else if hasFallback {
+		return fallback, nil
+	}
+	return "", fmt.Errorf("environment variable %v is not set", name)
+}
+
+func (i *interpolator) resolveKey(key string) (string, error) {
+	value, canonicalKey, ok := lookupKey(i.root, key)
+	if !ok {
+		return "", fmt.Errorf("key %v not found", key)
+	}
+
+	if resolved, ok := i.resolved[canonicalKey]; ok {
+		return resolved, nil
+	}
+
+	for _, k := range i.resolving {
+		if k == canonicalKey {
+			return "", fmt.Errorf("reference cycle %v -> %v", strings.Join(i.resolving, " -> "), canonicalKey)
+		}
+	}
+
+	if value.Kind() != reflect.String {
+		return fmt.Sprint(value.Interface()), nil
+	}
+
+	i.resolving = append(i.resolving, canonicalKey)
+	defer func() { i.resolving = i.resolving[:len(i.resolving)-1] }()
+
+	resolved, err := i.interpolate(value.String())
+	if err == nil {
+		i.resolved[canonicalKey] = resolved
+	}
+	return resolved, err
+}
+
+// walk interpolates all string values of v
+func (i *interpolator) walk(v reflect.Value, keys []string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return i.walk(v.Elem(), keys)
+		}
+	case reflect.Interface:
+		if !v.IsNil() && v.CanSet() {
+			elem := reflect.New(v.Elem().Type()).Elem()
+			elem.Set(v.Elem())
+			if err := i.walk(elem, keys); err != nil {
+				return err
+			}
+			v.Set(elem)
+		}
+	case reflect.Struct:
+		for idx := 0; idx < v.NumField(); idx++ {
+			if fieldStruct := v.Type().Field(idx); fieldStruct.PkgPath == "" {
+				if err := i.walk(v.Field(idx), append(keys, fieldStruct.Name)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < v.Len(); idx++ {
+			if err := i.walk(v.Index(idx), append(keys, strconv.Itoa(idx))); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, mapKey := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(mapKey))
+			if err := i.walk(elem, append(keys, fmt.Sprint(mapKey.Interface()))); err != nil {
+				return err
+			}
+			v.SetMapIndex(mapKey, elem)
+		}
+	case reflect.String:
+		key := strings.Join(keys, ".")
+		if !v.CanSet() || !strings.Contains(v.String(), "${") {
+			return nil
+		}
+
+		resolved, ok := i.resolved[key]
+		if !ok {
+			var err error
+			i.resolving = append(i.resolving, key)
+			resolved, err = i.interpolate(v.String())
+			i.resolving = i.resolving[:len(i.resolving)-1]
+			if err != nil {
+				if interpolationErr, ok := err.(*InterpolationError); ok {
+					interpolationErr.Key = key
+				}
+				return err
+			}
+			i.resolved[key] = resolved
+		}
+		v.SetString(resolved)
+	}
+	return nil
+}
+
+// lookupKey finds the value of a dot separated key like `DB.Host` or `Contacts.0.Name` in v,
+// field names are matched case insensitively, it returns the value and the key with canonical field names
+func lookupKey(v reflect.Value, key string) (reflect.Value, string, bool) {
+	var keys []string
+	for _, name := range strings.Split(key, ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return v, "", false
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			field, fieldKeys, ok := lookupField(v, name)
+			if !ok {
+				return v, "", false
+			}
+			v, keys = field, append(keys, fieldKeys...)
+		case reflect.Slice, reflect.Array:
+			idx, err := strconv.Atoi(name)
+			if err != nil || idx < 0 || idx >= v.Len() {
+				return v, "", false
+			}
+			v, keys = v.Index(idx), append(keys, name)
+		case reflect.Map:
+			var found bool
+			for _, mapKey := range v.MapKeys() {
+				if mapKeyName := fmt.Sprint(mapKey.Interface()); strings.EqualFold(mapKeyName, name) {
+					v, keys, found = v.MapIndex(mapKey), append(keys, mapKeyName), true
+					if mapKeyName == name {
+						break
+					}
+				}
+			}
+			if !found {
+				return v, "", false
+			}
+		default:
+			return v, "", false
+		}
+	}
+	return v, strings.Join(keys, "."), true
+}
+
+// lookupField finds field by name in struct v, including fields promoted from embedded structs
+func lookupField(v reflect.Value, name string) (reflect.Value, []string, bool) {
+	for idx := 0; idx < v.NumField(); idx++ {
+		if fieldStruct := v.Type().Field(idx); fieldStruct.PkgPath == "" && strings.EqualFold(fieldStruct.Name, name) {
+			return v.Field(idx), []string{fieldStruct.Name}, true
+		}
+	}
+
+	for idx := 0; idx < v.NumField(); idx++ {
+		if fieldStruct := v.Type().Field(idx); fieldStruct.Anonymous && reflect.Indirect(v.Field(idx)).Kind() == reflect.Struct {
+			if field, keys, ok := lookupField(reflect.Indirect(v.Field(idx)), name); ok {
+				return field, append([]string{fieldStruct.Name}, keys...), true
+			}
+		}
+	}
+	return v, nil, false
+}
+
+// processInterpolation resolves references in string values of config, files are the loaded
+// configuration files, used to report which file contains an unresolved reference
+func (configor *Configor) processInterpolation(config interface{}, files []string) error {
+	err := newInterpolator(configor, config).walk(reflect.ValueOf(config), nil)
+	if interpolationErr, ok := err.(*InterpolationError); ok {
+		interpolationErr.File = configor.findFileOfKey(config, files, interpolationErr.Key, interpolationErr.Reference)
+	}
+	return err
+}
+
+// findFileOfKey returns the last loaded file which sets key to a value containing str
+func (configor *Configor) findFileOfKey(config interface{}, files []string, key, str string) string {
+	for i := len(files) - 1; i >= 0; i-- {
+		fileConfig := reflect.New(reflect.Indirect(reflect.ValueOf(config)).Type())
+		if err := configor.processFile(fileConfig.Interface(), files[i], false); err == nil {
+			if value, _, ok := lookupKey(fileConfig, key); ok && value.Kind() == reflect.String && strings.Contains(value.String(), str) {
+				return files[i]
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package configor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Logger receives leveled diagnostics with structured key-value pairs, *slog.Logger satisfies this interface,
+// Debug mode, Verbose mode and Silent mode still decide which records are logged
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// printLogger prints records to writer like `msg key=value key=value`, used if Config.Logger is not set
+type printLogger struct {
+	writer io.Writer
+}
+
+func (l printLogger) Debug(msg string, args ...interface{}) { l.print(msg, args) }
+func (l printLogger) Info(msg string, args ...interface{})  { l.print(msg, args) }
+func (l printLogger) Warn(msg string, args ...interface{})  { l.print(msg, args) }
+func (l printLogger) Error(msg string, args ...interface{}) { l.print(msg, args) }
+
+func (l printLogger) print(msg string, args []interface{}) {
+	var builder strings.Builder
+	builder.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&builder, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&builder, " %v", args[i])
+		}
+	}
+	fmt.Fprintln(l.writer, builder.String())
+}
+
+// logger returns Config.Logger, or a logger printing to stdout if it is not set
+func (configor *Configor) logger() Logger {
+	if configor.Config.Logger != nil {
+		return configor.Config.Logger
+	}
+	return printLogger{writer: os.Stdout}
+}
@@ -0,0 +1,331 @@
+package configor
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Merge strategies of fields tagged with `merge`, used when multiple configuration files set the same field
+const (
+	// MergeReplace replaces the previous value, the default behaviour of slices
+	MergeReplace = "replace"
+	// MergeAppend appends slice elements, or adds map keys to the previous value
+	MergeAppend = "append"
+	// MergeDeep merges slice elements by index, or map values by key, recursively
+	MergeDeep = "deep"
+	// MergeKeyPrefix merges slice elements with the same key field, like `merge:"key=Name"`, and appends the others
+	MergeKeyPrefix = "key="
+)
+
+type mergeField struct {
+	index    []int
+	name     string
+	strategy string
+}
+
+type mergeFields []mergeField
+
+// getMergeFields returns fields with merge strategies of struct type t, including fields of nested structs
+func getMergeFields(t reflect.Type) (mergeFields, error) {
+	return collectMergeFields(t, nil, "", map[reflect.Type]bool{})
+}
+
+func collectMergeFields(t reflect.Type, index []int, prefix string, visited map[reflect.Type]bool) (mergeFields, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || visited[t] {
+		return nil, nil
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	var fields mergeFields
+	for i := 0; i < t.NumField(); i++ {
+		fieldStruct := t.Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+		if strategy := fieldStruct.Tag.Get("merge"); strategy != "" {
+			if err := validateMergeStrategy(fieldStruct.Type, strategy); err != nil {
+				return nil, fmt.Errorf("invalid merge strategy of field %v%v: %v", prefix, fieldStruct.Name, err)
+			}
+			fields = append(fields, mergeField{index: fieldIndex, name: prefix + fieldStruct.Name, strategy: strategy})
+			continue
+		}
+
+		nestedFields, err := collectMergeFields(fieldStruct.Type, fieldIndex, prefix+fieldStruct.Name+".", visited)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, nestedFields...)
+	}
+	return fields, nil
+}
+
+func validateMergeStrategy(t reflect.Type, strategy string) error {
+	switch {
+	case strategy == MergeReplace || strategy == MergeDeep:
+		return nil
+	case strategy == MergeAppend:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Map {
+			return fmt.Errorf("%v only supports slices and maps", strategy)
+		}
+	case strings.HasPrefix(strategy, MergeKeyPrefix):
+		if t.Kind() != reflect.Slice {
+			return fmt.Errorf("%v only supports slices of structs", strategy)
+		}
+
+		elemType := t.Elem()
+		for elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() != reflect.Struct {
+			return fmt.Errorf("%v only supports slices of structs", strategy)
+		} else if _, ok := elemType.FieldByName(strings.TrimPrefix(strategy, MergeKeyPrefix)); !ok {
+			return fmt.Errorf("key field %v not found", strings.TrimPrefix(strategy, MergeKeyPrefix))
+		}
+	default:
+		return fmt.Errorf("unknown merge strategy %v", strategy)
+	}
+	return nil
+}
+
+// values returns copies of fields' values in config, invalid values for fields inside nil pointers
+func (fields mergeFields) values(config interface{}) []reflect.Value {
+	values := make([]reflect.Value, len(fields))
+	for i, field := range fields {
+		if value, ok := fieldByIndex(reflect.ValueOf(config), field.index); ok {
+			values[i] = cloneValue(value)
+		}
+	}
+	return values
+}
+
+// merge merges fields' values set in fileConfig with previousValues, and sets the result to config, lines are keys
+// present in the file, see getFileKeys, values are set if their keys are present, or if they are not zero when lines
+// is nil, so a later file could override values with `false`, `0` or `""`
+func (fields mergeFields) merge(config interface{}, previousValues []reflect.Value, fileConfig interface{}, lines map[string]int) {
+	for i, field := range fields {
+		fileValue, ok := fieldByIndex(reflect.ValueOf(fileConfig), field.index)
+		if !ok {
+			continue
+		}
+
+		keys := getFieldKeys(reflect.ValueOf(fileConfig).Type(), field.index, lines)
+		if !isKeySet(fileValue, keys, lines) {
+			continue
+		}
+
+		value, ok := fieldByIndex(reflect.ValueOf(config), field.index)
+		if !ok {
+			continue
+		}
+
+		if previousValue := previousValues[i]; previousValue.IsValid() {
+			value.Set(mergeValue(previousValue, fileValue, field.strategy, keys, lines))
+		} else {
+			value.Set(cloneValue(fileValue))
+		}
+	}
+}
+
+func mergeValue(previous, value reflect.Value, strategy string, keys []string, lines map[string]int) reflect.Value {
+	switch {
+	case strategy == MergeAppend && value.Kind() == reflect.Slice:
+		return reflect.AppendSlice(cloneValue(previous), value)
+	case strategy == MergeAppend && value.Kind() == reflect.Map:
+		result := cloneValue(previous)
+		if result.IsNil() {
+			result = reflect.MakeMap(value.Type())
+		}
+		for _, key := range value.MapKeys() {
+			result.SetMapIndex(key, cloneValue(value.MapIndex(key)))
+		}
+		return result
+	case strategy == MergeDeep:
+		return deepMergeValue(previous, value, keys, lines)
+	case strings.HasPrefix(strategy, MergeKeyPrefix):
+		keyField := strings.TrimPrefix(strategy, MergeKeyPrefix)
+		result := cloneValue(previous)
+		for i := 0; i < value.Len(); i++ {
+			elem, elemKeys, merged := value.Index(i), appendKey(keys, strconv.Itoa(i)), false
+			for j := 0; j < result.Len() && isKeySet(elem, elemKeys, lines) && isValueSet(elem); j++ {
+				if isValueSet(result.Index(j)) && reflect.DeepEqual(reflect.Indirect(result.Index(j)).FieldByName(keyField).Interface(), reflect.Indirect(elem).FieldByName(keyField).Interface()) {
+					result.Index(j).Set(deepMergeValue(result.Index(j), elem, elemKeys, lines))
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				result = reflect.Append(result, cloneValue(elem))
+			}
+		}
+		return result
+	}
+	return cloneValue(value)
+}
+
+// deepMergeValue merges set values of value into previous recursively, slices are merged by index, maps by key,
+// keys are the path of value in the file, used to check if values are set, see isKeySet
+func deepMergeValue(previous, value reflect.Value, keys []string, lines map[string]int) reflect.Value {
+	if !isKeySet(value, keys, lines) {
+		return cloneValue(previous)
+	} else if !isValueSet(previous) || value.Kind() == reflect.Ptr && value.IsNil() {
+		return cloneValue(value)
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr:
+		result := reflect.New(value.Type().Elem())
+		result.Elem().Set(deepMergeValue(previous.Elem(), value.Elem(), keys, lines))
+		return result
+	case reflect.Struct:
+		if !hasExportedFields(value.Type()) {
+			// values like time.Time
+			return value
+		}
+
+		result := cloneValue(previous)
+		for i := 0; i < value.NumField(); i++ {
+			if result.Field(i).CanSet() {
+				fieldKeys := keys
+				if key, inline := getFieldKey(value.Type().Field(i), keys, lines); !inline {
+					fieldKeys = appendKey(keys, key)
+				}
+				result.Field(i).Set(deepMergeValue(previous.Field(i), value.Field(i), fieldKeys, lines))
+			}
+		}
+		return result
+	case reflect.Slice:
+		result := cloneValue(previous)
+		for i := 0; i < value.Len(); i++ {
+			if i < result.Len() {
+				result.Index(i).Set(deepMergeValue(result.Index(i), value.Index(i), appendKey(keys, strconv.Itoa(i)), lines))
+			} else {
+				result = reflect.Append(result, cloneValue(value.Index(i)))
+			}
+		}
+		return result
+	case reflect.Map:
+		result := cloneValue(previous)
+		for _, key := range value.MapKeys() {
+			if previousElem := result.MapIndex(key); previousElem.IsValid() {
+				result.SetMapIndex(key, deepMergeValue(previousElem, value.MapIndex(key), appendKey(keys, fmt.Sprint(key.Interface())), lines))
+			} else {
+				result.SetMapIndex(key, cloneValue(value.MapIndex(key)))
+			}
+		}
+		return result
+	}
+	return value
+}
+
+// getFieldKeys returns the path of keys of the nested field of struct type t by index in the file
+func getFieldKeys(t reflect.Type, index []int, lines map[string]int) (keys []string) {
+	for _, i := range index {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		fieldStruct := t.Field(i)
+		if key, inline := getFieldKey(fieldStruct, keys, lines); !inline {
+			keys = appendKey(keys, key)
+		}
+		t = fieldStruct.Type
+	}
+	return keys
+}
+
+// isKeySet returns true if the key of value is present in the file, or value is set if lines is nil
+func isKeySet(value reflect.Value, keys []string, lines map[string]int) bool {
+	if lines == nil {
+		return isValueSet(value)
+	}
+	_, ok := lines[strings.ToLower(strings.Join(keys, "."))]
+	return ok
+}
+
+func appendKey(keys []string, key string) []string {
+	return append(append([]string{}, keys...), key)
+}
+
+// isValueSet returns true if value is set by the configuration file, slices and maps are set if not nil,
+// so an empty list in the file could clear the previous value
+func isValueSet(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+		return !value.IsNil()
+	}
+	return !value.IsZero()
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldByIndex returns the nested field of struct v by index, returns false if a pointer on the way is nil
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for _, i := range index {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+// cloneValue returns a deep copy of v, so decoders reusing slices and maps won't change it
+func cloneValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			result := reflect.New(v.Type().Elem())
+			result.Elem().Set(cloneValue(v.Elem()))
+			return result
+		}
+	case reflect.Struct:
+		result := reflect.New(v.Type()).Elem()
+		result.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if result.Field(i).CanSet() {
+				result.Field(i).Set(cloneValue(v.Field(i)))
+			}
+		}
+		return result
+	case reflect.Slice:
+		if !v.IsNil() {
+			result := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+			for i := 0; i < v.Len(); i++ {
+				result.Index(i).Set(cloneValue(v.Index(i)))
+			}
+			return result
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			result := reflect.MakeMapWithSize(v.Type(), v.Len())
+			for _, key := range v.MapKeys() {
+				result.SetMapIndex(key, cloneValue(v.MapIndex(key)))
+			}
+			return result
+		}
+	}
+
+	// copy v, as v might be an addressable field which will be changed later
+	result := reflect.New(v.Type()).Elem()
+	result.Set(v)
+	return result
+}
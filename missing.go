@@ -0,0 +1,105 @@
+package configor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// optionalFilePrefix marks files which could be missing, see Optional
+const optionalFilePrefix = "\x00optional:"
+
+// Optional marks file as optional, no error is returned if it is missing even with Config.ErrorOnMissingFiles,
+// like `configor.Load(&Config, "config.yml", configor.Optional("config.local.yml"))`
+func Optional(file string) string {
+	return optionalFilePrefix + file
+}
+
+// stripOptionalFiles returns files without optional markers, used to log files
+func stripOptionalFiles(files []string) []string {
+	stripped := make([]string, len(files))
+	for i, file := range files {
+		stripped[i], _ = parseOptionalFile(file)
+	}
+	return stripped
+}
+
+// parseOptionalFile returns file without the optional marker, and whether it is optional
+func parseOptionalFile(file string) (string, bool) {
+	if strings.HasPrefix(file, optionalFilePrefix) {
+		return strings.TrimPrefix(file, optionalFilePrefix), true
+	}
+	return file, false
+}
+
+// MissingFileError is returned if a configuration file can't be found with Config.ErrorOnMissingFiles
+type MissingFileError struct {
+	File string
+	// Candidates are all paths checked, including search paths, environment and example variants
+	Candidates []string
+}
+
+func (e *MissingFileError) Error() string {
+	return fmt.Sprintf("failed to find configuration %v, tried %v", e.File, strings.Join(e.Candidates, ", "))
+}
+
+// getCandidateFiles returns paths checked for file in search paths tried, including environment and example variants
+// and overrides
+func (configor *Configor) getCandidateFiles(file string, tried []string) []string {
+	if len(tried) == 0 {
+		tried = []string{file}
+	}
+
+	var candidates []string
+	for _, f := range tried {
+		candidates = append(candidates, f)
+		for _, env := range configor.getFileVariants() {
+			candidates = append(candidates, getConfigurationFileName(f, env))
+		}
+	}
+	return candidates
+}
+
+// ExampleFallbackError is returned if a configuration file is missing and its example variant is used,
+// with Config.ErrorOnExampleFallback
+type ExampleFallbackError struct {
+	File        string
+	Example     string
+	Environment string
+}
+
+func (e *ExampleFallbackError) Error() string {
+	return fmt.Sprintf("failed to find configuration %v in environment %v, refused to fall back to example file %v", e.File, e.Environment, e.Example)
+}
+
+// getExampleSuffix returns Config.ExampleSuffix, default - `example`, like `config.example.yml`
+func (configor *Configor) getExampleSuffix() string {
+	if configor.Config.ExampleSuffix != "" {
+		return configor.Config.ExampleSuffix
+	}
+	return "example"
+}
+
+// isExampleFallbackAllowed returns true if example files could be used in the current environment
+func (configor *Configor) isExampleFallbackAllowed() bool {
+	if configor.Config.ExampleEnvironments == nil {
+		return true
+	}
+
+	env := configor.GetEnvironment()
+	for _, allowed := range configor.Config.ExampleEnvironments {
+		if env == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// getFileVariants returns environments of the chain, the example suffix if example fallback is allowed, then enabled
+// overrides, as a lone override counts as a found configuration
+func (configor *Configor) getFileVariants() []string {
+	chain, _ := configor.getEnvironmentChain()
+	if configor.isExampleFallbackAllowed() {
+		chain = append(chain, configor.getExampleSuffix())
+	}
+	return append(chain, configor.getOverrides()...)
+}
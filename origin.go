@@ -0,0 +1,348 @@
+package configor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// OriginKind is the kind of source a configuration value was loaded from
+type OriginKind string
+
+const (
+	OriginDefault OriginKind = "default"
+	OriginFile    OriginKind = "file"
+	OriginEnv     OriginKind = "env"
+	OriginFlag    OriginKind = "flag"
+)
+
+// Origin describes where a configuration value was loaded from
+type Origin struct {
+	Kind OriginKind
+	// File is the configuration file the value was loaded from
+	File string
+	// Line is the line of the value in File, only available for YAML files, 0 if unknown
+	Line int
+	// Env is the name of the shell environment the value was loaded from
+	Env string
+	// Flag is the name of the command line flag the value was loaded from
+	Flag string
+}
+
+func (origin Origin) String() string {
+	switch origin.Kind {
+	case OriginFile:
+		if origin.Line > 0 {
+			return fmt.Sprintf("file %v:%v", origin.File, origin.Line)
+		}
+		return fmt.Sprintf("file %v", origin.File)
+	case OriginEnv:
+		return fmt.Sprintf("env %v", origin.Env)
+	case OriginFlag:
+		return fmt.Sprintf("flag --%v", origin.Flag)
+	}
+	return string(origin.Kind)
+}
+
+// Origin returns where the value of key was loaded from by the last successful load, key is the path of the field
+// like `DB.Host`, map keys and slice indexes are part of the path like `Upstreams.billing.Timeout` or `Contacts.0.Name`,
+// sources override each other in order, so the origin of the source that set the value last is returned
+func (configor *Configor) Origin(key string) (Origin, bool) {
+	configor.originsMutex.RLock()
+	defer configor.originsMutex.RUnlock()
+
+	if origin, ok := configor.origins[key]; ok {
+		return origin, true
+	}
+
+	for k, origin := range configor.origins {
+		if strings.EqualFold(k, key) {
+			return origin, true
+		}
+	}
+	return Origin{}, false
+}
+
+// recordOrigin records the origin of the value at path for the configuration being loaded
+func (configor *Configor) recordOrigin(path []string, origin Origin) {
+	configor.originsMutex.Lock()
+	defer configor.originsMutex.Unlock()
+
+	if configor.loadingOrigins != nil {
+		configor.loadingOrigins[strings.Join(path, ".")] = origin
+	}
+}
+
+// getLoadingOrigin returns the origin of the value at path recorded for the configuration being loaded
+func (configor *Configor) getLoadingOrigin(path string) (Origin, bool) {
+	configor.originsMutex.RLock()
+	defer configor.originsMutex.RUnlock()
+
+	origin, ok := configor.loadingOrigins[path]
+	return origin, ok
+}
+
+// recordFileOrigins records origins of values set by file, it decodes the file separately to find out values it sets,
+// values are only recorded if their keys are present in the file, so zero values like `debug: false` are recorded too
+func (configor *Configor) recordFileOrigins(config interface{}, file string) error {
+	configurationFile, err := configor.readConfigurationFile(file, configor.getIncludeKey(config))
+	if err != nil {
+		return err
+	}
+
+	fileConfig := reflect.New(reflect.Indirect(reflect.ValueOf(config)).Type())
+	if err := decodeConfigurationData(fileConfig.Interface(), file, configurationFile.data, false); err != nil {
+		return err
+	}
+
+	configor.recordValueOrigins(fileConfig.Elem(), nil, nil, file, configurationFile.getKeys(file))
+	return nil
+}
+
+// getKeys returns lower case paths of keys present in the file, with lines of them for YAML files, keys are parsed
+// once, returns nil if keys can't be found
+func (f *configurationFile) getKeys(file string) map[string]int {
+	f.keysOnce.Do(func() {
+		f.keys = getFileKeys(file, f.raw, f.data)
+	})
+	return f.keys
+}
+
+// getFileKeys returns lower case paths of keys present in file, raw is the original content of file, data is the
+// decrypted content, returns nil if keys can't be found
+func getFileKeys(file string, raw, data []byte) map[string]int {
+	parseYAML := func() map[string]int {
+		// lines are looked up from the original file, as data may be re-encoded when extracting include directives
+		for _, content := range [][]byte{raw, data} {
+			var node yaml.Node
+			if yaml.Unmarshal(content, &node) == nil && len(node.Content) > 0 {
+				lines := map[string]int{}
+				getYAMLLines(&node, nil, lines)
+				return lines
+			}
+		}
+		return nil
+	}
+
+	parseJSON := func() map[string]int {
+		var values map[string]interface{}
+		if json.Unmarshal(data, &values) != nil {
+			return nil
+		}
+		keys := map[string]int{}
+		getValueKeys(values, nil, keys)
+		return keys
+	}
+
+	parseTOML := func() map[string]int {
+		var values map[string]interface{}
+		if _, err := toml.Decode(string(data), &values); err != nil {
+			return nil
+		}
+		keys := map[string]int{}
+		getValueKeys(values, nil, keys)
+		return keys
+	}
+
+	switch {
+	case strings.HasSuffix(file, ".yaml") || strings.HasSuffix(file, ".yml"):
+		return parseYAML()
+	case strings.HasSuffix(file, ".toml"):
+		return parseTOML()
+	case strings.HasSuffix(file, ".json"):
+		return parseJSON()
+	}
+
+	// same order as decodeConfigurationData
+	for _, parse := range []func() map[string]int{parseTOML, parseJSON, parseYAML} {
+		if keys := parse(); keys != nil {
+			return keys
+		}
+	}
+	return nil
+}
+
+// recordValueOrigins records origins of values set in v, keys are paths of keys in the file, lines are lines of keys
+// present in the file, keyed by lower case paths, values are recorded if they are not zero when lines is nil
+func (configor *Configor) recordValueOrigins(v reflect.Value, path, keys []string, file string, lines map[string]int) bool {
+	var (
+		key          = strings.ToLower(strings.Join(keys, "."))
+		line, exists = lines[key]
+		present      = exists && len(keys) > 0
+	)
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if present && len(path) > 0 {
+				configor.recordOrigin(path, Origin{Kind: OriginFile, File: file, Line: line})
+			}
+			return present
+		}
+		v = v.Elem()
+	}
+
+	var recorded bool
+	switch v.Kind() {
+	case reflect.Struct:
+		if !hasExportedFields(v.Type()) {
+			recorded = !v.IsZero()
+			break
+		}
+
+		for i := 0; i < v.NumField(); i++ {
+			fieldStruct := v.Type().Field(i)
+			if fieldStruct.PkgPath != "" {
+				continue
+			}
+
+			fieldKey, inline := getFieldKey(fieldStruct, keys, lines)
+			if fieldKey == "-" {
+				continue
+			}
+
+			if inline {
+				// values of inlined structs are accessible from both the embedded struct and the parent
+				configor.recordValueOrigins(v.Field(i), append(path, fieldStruct.Name), keys, file, lines)
+				if configor.recordValueOrigins(v.Field(i), path, keys, file, lines) {
+					recorded = true
+				}
+			} else if configor.recordValueOrigins(v.Field(i), append(path, fieldStruct.Name), append(keys, fieldKey), file, lines) {
+				recorded = true
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			keyName := fmt.Sprint(k.Interface())
+			if configor.recordValueOrigins(v.MapIndex(k), append(path, keyName), append(keys, keyName), file, lines) {
+				recorded = true
+			}
+		}
+		recorded = recorded || !v.IsNil()
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			configor.recordValueOrigins(v.Index(i), append(path, fmt.Sprint(i)), append(keys, fmt.Sprint(i)), file, lines)
+		}
+		recorded = v.Kind() == reflect.Array && !v.IsZero() || v.Kind() == reflect.Slice && !v.IsNil()
+	default:
+		recorded = !v.IsZero()
+	}
+
+	if lines != nil {
+		recorded = recorded || present
+		if v.Kind() != reflect.Struct || !hasExportedFields(v.Type()) {
+			recorded = present
+		}
+	}
+
+	if recorded && len(path) > 0 {
+		configor.recordOrigin(path, Origin{Kind: OriginFile, File: file, Line: line})
+	}
+	return recorded
+}
+
+// getFieldKey returns the key of field present in the file under keys, json and toml tags are checked as the file
+// might not be a YAML file, fields of embedded structs are inlined if their keys are not present, like in json files
+func getFieldKey(fieldStruct reflect.StructField, keys []string, lines map[string]int) (string, bool) {
+	yamlKey, inline := getYAMLKey(fieldStruct)
+	if inline || yamlKey == "-" || lines == nil {
+		return yamlKey, inline
+	}
+
+	candidates := []string{yamlKey, strings.Split(fieldStruct.Tag.Get("json"), ",")[0], strings.Split(fieldStruct.Tag.Get("toml"), ",")[0], fieldStruct.Name}
+	for _, candidate := range candidates {
+		if candidate == "" || candidate == "-" {
+			continue
+		}
+		if _, ok := lines[strings.ToLower(strings.Join(append(append([]string{}, keys...), candidate), "."))]; ok {
+			return candidate, false
+		}
+	}
+	return yamlKey, fieldStruct.Anonymous
+}
+
+// getValueKeys collects lower case paths of keys in values decoded from json or toml files
+func getValueKeys(value interface{}, keys []string, lines map[string]int) {
+	set := func(key string, v interface{}) {
+		path := append(append([]string{}, keys...), key)
+		lines[strings.ToLower(strings.Join(path, "."))] = 0
+		getValueKeys(v, path, lines)
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			set(k, v)
+		}
+	case []interface{}:
+		for i, v := range value {
+			set(fmt.Sprint(i), v)
+		}
+	case []map[string]interface{}:
+		for i, v := range value {
+			set(fmt.Sprint(i), v)
+		}
+	}
+}
+
+// getYAMLKey returns the key of field in YAML files, and whether the field is inlined
+func getYAMLKey(fieldStruct reflect.StructField) (string, bool) {
+	name := strings.Split(fieldStruct.Tag.Get("yaml"), ",")
+	for _, option := range name[1:] {
+		if option == "inline" {
+			return "", true
+		}
+	}
+
+	if name[0] != "" {
+		return name[0], false
+	}
+	return strings.ToLower(fieldStruct.Name), false
+}
+
+// getYAMLLines collects lines of values in node, keyed by lower case paths of YAML keys
+func getYAMLLines(node *yaml.Node, keys []string, lines map[string]int) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			getYAMLLines(n, keys, lines)
+		}
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			getYAMLLines(node.Alias, keys, lines)
+		}
+	case yaml.MappingNode:
+		var mergeNodes []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			if keyNode.Value == "<<" {
+				mergeNodes = append(mergeNodes, valueNode)
+				continue
+			}
+
+			path := append(keys, keyNode.Value)
+			lines[strings.ToLower(strings.Join(path, "."))] = keyNode.Line
+			getYAMLLines(valueNode, path, lines)
+		}
+
+		// values of merge keys are reported with lines of anchors, unless they are overridden
+		for _, mergeNode := range mergeNodes {
+			mergedLines := map[string]int{}
+			getYAMLLines(mergeNode, keys, mergedLines)
+			for key, line := range mergedLines {
+				if _, ok := lines[key]; !ok {
+					lines[key] = line
+				}
+			}
+		}
+	case yaml.SequenceNode:
+		for i, n := range node.Content {
+			path := append(keys, fmt.Sprint(i))
+			lines[strings.ToLower(strings.Join(path, "."))] = n.Line
+			getYAMLLines(n, path, lines)
+		}
+	}
+}
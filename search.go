@@ -0,0 +1,74 @@
+package configor
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// getSearchPaths returns Config.SearchPaths with shell environments expanded, paths referencing unset environments
+// are skipped, like `$XDG_CONFIG_HOME/app` if XDG_CONFIG_HOME is not set
+func (configor *Configor) getSearchPaths() []string {
+	var searchPaths []string
+	for _, searchPath := range configor.Config.SearchPaths {
+		var missing bool
+		expanded := os.Expand(searchPath, func(name string) string {
+			value := configor.getenv(name)
+			if value == "" {
+				missing = true
+			}
+			return value
+		})
+
+		if !missing {
+			searchPaths = append(searchPaths, expanded)
+		}
+	}
+	return searchPaths
+}
+
+// resolveSearchPath resolves relative file against Config.SearchPaths, returns the file in the first search path
+// containing the file, its environment variants or example variant, and all paths tried
+func (configor *Configor) resolveSearchPath(file string) (string, []string) {
+	searchPaths := configor.getSearchPaths()
+	if len(searchPaths) == 0 || path.IsAbs(file) || filepath.IsAbs(file) {
+		return file, nil
+	}
+
+	join := filepath.Join
+	if configor.FS != nil {
+		join = path.Join
+	}
+
+	var tried []string
+	for _, searchPath := range searchPaths {
+		candidate := join(searchPath, file)
+		tried = append(tried, candidate)
+		if configor.Config.Debug || configor.Config.Verbose {
+			configor.logger().Info("Searching configuration file", "file", candidate)
+		}
+
+		if configor.configurationFileExists(candidate) {
+			return candidate, tried
+		}
+	}
+	return tried[0], tried
+}
+
+// configurationFileExists returns true if file, glob pattern or directory, or its environment or example variants exist
+func (configor *Configor) configurationFileExists(file string) bool {
+	if expandedFiles, expanded, err := configor.expandConfigurationFiles(file); expanded {
+		return err == nil && len(expandedFiles) > 0
+	}
+
+	if fileInfo, err := configor.stat(file); err == nil && fileInfo.Mode().IsRegular() {
+		return true
+	}
+
+	for _, env := range configor.getFileVariants() {
+		if _, _, err := configor.getConfigurationFileWithENVPrefix(file, env); err == nil {
+			return true
+		}
+	}
+	return false
+}
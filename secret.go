@@ -0,0 +1,131 @@
+package configor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// SecretProvider resolves secret references, ref is the part of the reference after the scheme,
+// like `///run/secrets/db_password` for `file:///run/secrets/db_password`
+type SecretProvider interface {
+	GetSecret(ref string) (string, error)
+}
+
+// SecretProviderFunc is an adapter to use ordinary functions as secret providers
+type SecretProviderFunc func(ref string) (string, error)
+
+// GetSecret returns f(ref)
+func (f SecretProviderFunc) GetSecret(ref string) (string, error) {
+	return f(ref)
+}
+
+// FileSecretProvider reads secrets from files, like Docker or Kubernetes secret mounts, trailing newlines are trimmed,
+// relative paths are resolved against Dir
+type FileSecretProvider struct {
+	Dir string
+}
+
+// GetSecret reads the secret from file `ref`
+func (p FileSecretProvider) GetSecret(ref string) (string, error) {
+	path := ref
+	if strings.HasPrefix(path, "//") {
+		path = path[2:]
+	}
+
+	if p.Dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(p.Dir, path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// MemorySecretProvider returns secrets from the map, useful for tests
+type MemorySecretProvider map[string]string
+
+// GetSecret returns the secret of ref from the map
+func (p MemorySecretProvider) GetSecret(ref string) (string, error) {
+	if value, ok := p[ref]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("secret %v not found", ref)
+}
+
+// resolveSecret resolves value if it is a reference with a registered scheme, like `file:///run/secrets/db_password`
+func (configor *Configor) resolveSecret(value string) (string, bool, error) {
+	idx := strings.Index(value, ":")
+	if idx <= 0 {
+		return value, false, nil
+	}
+
+	provider, ok := configor.Config.SecretProviders[value[:idx]]
+	if !ok || provider == nil {
+		return value, false, nil
+	}
+
+	secret, err := provider.GetSecret(value[idx+1:])
+	return secret, true, err
+}
+
+// processSecrets replaces secret references in string values of v with secrets, path is the field names leading to v
+func (configor *Configor) processSecrets(v reflect.Value, path []string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return configor.processSecrets(v.Elem(), path)
+		}
+	case reflect.Interface:
+		if !v.IsNil() && v.CanSet() {
+			elem := reflect.New(v.Elem().Type()).Elem()
+			elem.Set(v.Elem())
+			if err := configor.processSecrets(elem, path); err != nil {
+				return err
+			}
+			v.Set(elem)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if fieldStruct := v.Type().Field(i); fieldStruct.PkgPath == "" {
+				if err := configor.processSecrets(v.Field(i), append(path, fieldStruct.Name)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := configor.processSecrets(v.Index(i), append(path, fmt.Sprint(i))); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			if err := configor.processSecrets(elem, append(path, fmt.Sprint(key.Interface()))); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+
+		secret, ok, err := configor.resolveSecret(v.String())
+		if err != nil {
+			return fmt.Errorf("failed to resolve secret of %v, got %v", strings.Join(path, "."), err)
+		} else if ok {
+			if configor.Config.Debug || configor.Config.Verbose {
+				configor.logger().Info("Loading configuration for field from secret", "field", strings.Join(path, "."))
+			}
+			v.SetString(secret)
+		}
+	}
+	return nil
+}
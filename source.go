@@ -0,0 +1,108 @@
+package configor
+
+// Source loads configurations into the config struct, sources are loaded in the order of Config.Sources,
+// values loaded by later sources overwrite values of earlier sources
+type Source interface {
+	load(l *loader) error
+}
+
+// loader holds the state of loading a config from sources
+type loader struct {
+	configor    *Configor
+	config      interface{}
+	files       []string
+	flags       []*flagValue
+	configFiles map[*filesSource][]string
+}
+
+// SourceFunc is a custom source, which loads configurations into config
+type SourceFunc func(config interface{}) error
+
+func (fc SourceFunc) load(l *loader) error {
+	return fc(l.config)
+}
+
+// DefaultSources returns the default sources: defaults, files, shell environments, flags
+func DefaultSources() []Source {
+	return []Source{Defaults(), Files(), Env(), Flags()}
+}
+
+func (configor *Configor) getSources() []Source {
+	if configor.Config.Sources != nil {
+		return configor.Config.Sources
+	}
+	return DefaultSources()
+}
+
+type defaultsSource struct{}
+
+// Defaults returns a source to set values of `default` tags
+func Defaults() Source {
+	return defaultsSource{}
+}
+
+func (defaultsSource) load(l *loader) error {
+	return l.configor.processDefaults(l.config, l.config, nil, false)
+}
+
+type filesSource struct {
+	files []string
+}
+
+// Files returns a source to load configuration files, files passed to Load are loaded if no files given
+func Files(files ...string) Source {
+	return &filesSource{files: files}
+}
+
+func (s *filesSource) getFiles(files []string) []string {
+	if len(s.files) > 0 {
+		return s.files
+	}
+	return files
+}
+
+func (s *filesSource) load(l *loader) error {
+	configor := l.configor
+	for _, file := range l.configFiles[s] {
+		if configor.Config.Debug || configor.Config.Verbose {
+			configor.logger().Info("Loading configurations from file", "file", file)
+		}
+		if err := configor.processFile(l.config, file, configor.GetErrorOnUnmatchedKeys()); err != nil {
+			return err
+		}
+		if err := configor.recordFileOrigins(l.config, file); err != nil {
+			return err
+		}
+	}
+
+	// resolve references in configuration files
+	if !configor.Config.Interpolate {
+		return nil
+	}
+	return configor.processInterpolation(l.config, l.configFiles[s])
+}
+
+type envSource struct{}
+
+// Env returns a source to load shell environments
+func Env() Source {
+	return envSource{}
+}
+
+func (envSource) load(l *loader) error {
+	if prefix := l.configor.getENVPrefix(l.config); prefix != "-" {
+		return l.configor.processTags(l.config, nil, prefix)
+	}
+	return l.configor.processTags(l.config, nil)
+}
+
+type flagsSource struct{}
+
+// Flags returns a source to load flags bound with BindFlags
+func Flags() Source {
+	return flagsSource{}
+}
+
+func (flagsSource) load(l *loader) error {
+	return l.configor.processFlags(l.config, l.flags)
+}
@@ -10,7 +10,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -32,7 +34,7 @@ func (e *UnmatchedTomlKeysError) Error() string {
 
 func (configor *Configor) getENVPrefix(config interface{}) string {
 	if configor.Config.ENVPrefix == "" {
-		if prefix := os.Getenv("CONFIGOR_ENV_PREFIX"); prefix != "" {
+		if prefix := configor.getenv("CONFIGOR_ENV_PREFIX"); prefix != "" {
 			return prefix
 		}
 		return "Configor"
@@ -40,91 +42,278 @@ func (configor *Configor) getENVPrefix(config interface{}) string {
 	return configor.Config.ENVPrefix
 }
 
-func (c *Configor) getConfigurationFileWithENVPrefix(file, env string) (string, time.Time, error) {
-	stat := os.Stat
-	if c.FS != nil {
-		stat = func(name string) (os.FileInfo, error) {
-			return fs.Stat(c.FS, name)
-		}
+func (configor *Configor) stat(name string) (os.FileInfo, error) {
+	if configor.FS != nil {
+		return fs.Stat(configor.FS, name)
 	}
-	var (
-		envFile string
-		extname = path.Ext(file)
-	)
+	return os.Stat(name)
+}
 
-	if extname == "" {
-		envFile = fmt.Sprintf("%v.%v", file, env)
-	} else {
-		envFile = fmt.Sprintf("%v.%v%v", strings.TrimSuffix(file, extname), env, extname)
+func (configor *Configor) readFile(name string) ([]byte, error) {
+	if configor.FS != nil {
+		return fs.ReadFile(configor.FS, name)
 	}
+	return ioutil.ReadFile(name)
+}
+
+// getConfigurationFileName returns the name of env variant of file, like `config.production.yml` for `config.yml`
+func getConfigurationFileName(file, env string) string {
+	if extname := path.Ext(file); extname != "" {
+		return fmt.Sprintf("%v.%v%v", strings.TrimSuffix(file, extname), env, extname)
+	}
+	return fmt.Sprintf("%v.%v", file, env)
+}
 
-	if fileInfo, err := stat(envFile); err == nil && fileInfo.Mode().IsRegular() {
+func (c *Configor) getConfigurationFileWithENVPrefix(file, env string) (string, time.Time, error) {
+	envFile := getConfigurationFileName(file, env)
+	if fileInfo, err := c.stat(envFile); err == nil && fileInfo.Mode().IsRegular() {
 		return envFile, fileInfo.ModTime(), nil
 	}
 	return "", time.Now(), fmt.Errorf("failed to find file %v", file)
 }
 
-func (configor *Configor) getConfigurationFiles(config *Config, watchMode bool, files ...string) ([]string, map[string]time.Time) {
-	stat := os.Stat
-	if config.FS != nil {
-		stat = func(name string) (os.FileInfo, error) {
-			return fs.Stat(config.FS, name)
+// getConfigurationFiles returns configuration files to load in order, and their modification times, includeKey is the
+// key of include directives, see getIncludeKey
+func (configor *Configor) getConfigurationFiles(watchMode bool, includeKey string, files ...string) ([]string, map[string]time.Time, error) {
+	var resultKeys []string
+	var results = map[string]time.Time{}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		file, optional := parseOptionalFile(files[i])
+		file, tried := configor.resolveSearchPath(file)
+
+		// check glob patterns and directories
+		if expandedFiles, expanded, err := configor.expandConfigurationFiles(file); err != nil {
+			return nil, nil, err
+		} else if expanded {
+			if len(expandedFiles) == 0 {
+				if configor.Config.ErrorOnMissingFiles && !optional {
+					if len(tried) == 0 {
+						tried = []string{file}
+					}
+					return nil, nil, &MissingFileError{File: files[i], Candidates: tried}
+				} else if !optional && !configor.Silent {
+					configor.logger().Warn("Failed to find configuration", "file", file)
+				}
+			}
+
+			for _, expandedFile := range expandedFiles {
+				// expanded files might be virtual bases of variants, skip them silently if nothing is found
+				if _, err := configor.appendConfigurationFileWithVariants(&resultKeys, results, expandedFile, watchMode, includeKey); err != nil {
+					return nil, nil, err
+				}
+			}
+			continue
+		}
+
+		if found, err := configor.appendConfigurationFileWithVariants(&resultKeys, results, file, watchMode, includeKey); err != nil {
+			return nil, nil, err
+		} else if !found {
+			if configor.Config.ErrorOnMissingFiles && !optional {
+				return nil, nil, &MissingFileError{File: files[i], Candidates: configor.getCandidateFiles(file, tried)}
+			} else if !optional && !configor.Silent {
+				configor.logger().Warn("Failed to find configuration", "file", file)
+			}
 		}
 	}
+	return resultKeys, results, nil
+}
 
-	var resultKeys []string
-	var results = map[string]time.Time{}
+// appendConfigurationFileWithVariants appends file, its environment variants, its example file if none of them are
+// found, then its overrides to the loading list, returns false if nothing is found, a lone override counts as found
+func (configor *Configor) appendConfigurationFileWithVariants(resultKeys *[]string, results map[string]time.Time, file string, watchMode bool, includeKey string) (bool, error) {
+	var foundFile bool
 
-	if !watchMode && (configor.Config.Debug || configor.Config.Verbose) {
-		fmt.Printf("Current environment: '%v'\n", configor.GetEnvironment())
+	// check configuration
+	if fileInfo, err := configor.stat(file); err == nil && fileInfo.Mode().IsRegular() {
+		foundFile = true
+		if err := configor.appendConfigurationFile(resultKeys, results, file, fileInfo.ModTime(), nil, includeKey); err != nil {
+			return foundFile, err
+		}
 	}
 
-	for i := len(files) - 1; i >= 0; i-- {
-		foundFile := false
-		file := files[i]
+	// check configuration with env
+	if found, err := configor.appendEnvironmentFiles(resultKeys, results, file, nil, includeKey); err != nil {
+		return foundFile, err
+	} else if found {
+		foundFile = true
+	}
 
-		// check configuration
-		if fileInfo, err := stat(file); err == nil && fileInfo.Mode().IsRegular() {
+	// check example configuration, it is still used as the base if only overrides are found
+	if !foundFile {
+		if example, modTime, err := configor.getConfigurationFileWithENVPrefix(file, configor.getExampleSuffix()); err == nil && configor.isExampleFallbackAllowed() {
+			if configor.Config.ErrorOnExampleFallback {
+				return foundFile, &ExampleFallbackError{File: file, Example: example, Environment: configor.GetEnvironment()}
+			} else if !watchMode && !configor.Silent {
+				configor.logger().Warn("Failed to find configuration, using example file", "file", file, "example", example, "environment", configor.GetEnvironment())
+			}
 			foundFile = true
-			resultKeys = append(resultKeys, file)
-			results[file] = fileInfo.ModTime()
+			if err := configor.appendConfigurationFile(resultKeys, results, example, modTime, nil, includeKey); err != nil {
+				return foundFile, err
+			}
 		}
+	}
 
-		// check configuration with env
-		if file, modTime, err := configor.getConfigurationFileWithENVPrefix(file, configor.GetEnvironment()); err == nil {
-			foundFile = true
-			resultKeys = append(resultKeys, file)
-			results[file] = modTime
+	// check overrides, appended after the example configuration, so their values always win
+	if found, err := configor.appendOverrideFiles(resultKeys, results, file, nil, includeKey); err != nil {
+		return foundFile, err
+	} else if found {
+		foundFile = true
+	}
+	return foundFile, nil
+}
+
+var configurationFileExts = []string{".yaml", ".yml", ".toml", ".json"}
+
+// expandConfigurationFiles expands glob patterns and directories to a sorted list of configuration files,
+// directories are expanded to files with known extensions, variants of expanded files are replaced with their
+// base files, which might not exist, as variants are loaded with base files, returns false if file is not a glob
+// pattern or directory
+func (configor *Configor) expandConfigurationFiles(file string) ([]string, bool, error) {
+	var (
+		join    = filepath.Join
+		matches []string
+	)
+	if configor.FS != nil {
+		join = path.Join
+	}
+
+	if strings.ContainsAny(file, "*?[") {
+		var err error
+		if configor.FS != nil {
+			matches, err = fs.Glob(configor.FS, file)
+		} else {
+			matches, err = filepath.Glob(file)
+		}
+		if err != nil {
+			return nil, true, err
+		}
+	} else if fileInfo, err := configor.stat(file); err == nil && fileInfo.IsDir() {
+		var entries []fs.DirEntry
+		if configor.FS != nil {
+			entries, err = fs.ReadDir(configor.FS, file)
+		} else {
+			entries, err = os.ReadDir(file)
+		}
+		if err != nil {
+			return nil, true, err
 		}
 
-		// check example configuration
-		if !foundFile {
-			if example, modTime, err := configor.getConfigurationFileWithENVPrefix(file, "example"); err == nil {
-				if !watchMode && !configor.Silent {
-					fmt.Printf("Failed to find configuration %v, using example file %v\n", file, example)
+		for _, entry := range entries {
+			for _, ext := range configurationFileExts {
+				if path.Ext(entry.Name()) == ext {
+					matches = append(matches, join(file, entry.Name()))
 				}
-				resultKeys = append(resultKeys, example)
-				results[example] = modTime
-			} else if !configor.Silent {
-				fmt.Printf("Failed to find configuration %v\n", file)
 			}
 		}
+	} else {
+		return nil, false, nil
+	}
+
+	var (
+		expandedFiles []string
+		exists        = map[string]bool{}
+		variantNames  = configor.getVariantNames()
+	)
+	for _, match := range matches {
+		exists[match] = true
+	}
+
+	for _, match := range matches {
+		if fileInfo, err := configor.stat(match); err != nil || !fileInfo.Mode().IsRegular() {
+			continue
+		}
+
+		// variants like config.production.yml are replaced with their base file config.yml, even if it doesn't exist,
+		// so they are only loaded if selected by the current environment, example fallback or overrides
+		extname := path.Ext(match)
+		if stem := strings.TrimSuffix(match, extname); path.Ext(stem) != "" {
+			base := strings.TrimSuffix(stem, path.Ext(stem)) + extname
+			if variantNames[strings.TrimPrefix(path.Ext(stem), ".")] || exists[base] {
+				match = base
+			}
+		}
+
+		if !containsString(expandedFiles, match) {
+			expandedFiles = append(expandedFiles, match)
+		}
 	}
-	return resultKeys, results
+	sort.Strings(expandedFiles)
+	return expandedFiles, true, nil
 }
 
-func (c *Configor) processFile(config interface{}, file string, errorOnUnmatchedKeys bool) error {
-	readFile := ioutil.ReadFile
-	if c.FS != nil {
-		readFile = func(filename string) ([]byte, error) {
-			return fs.ReadFile(c.FS, filename)
+// wellKnownEnvironments are names of environments recognized as variants of expanded files without declaration
+var wellKnownEnvironments = []string{"development", "test", "staging", "production"}
+
+// getVariantNames returns names recognized as variants in file names like `config.<variant>.yml`, including
+// environments of the chain and declared in Config, the example suffix, `local` and the hostname
+func (configor *Configor) getVariantNames() map[string]bool {
+	names := map[string]bool{configor.getExampleSuffix(): true, "local": true}
+	if hostname := configor.getHostname(); hostname != "" {
+		names[hostname] = true
+	}
+
+	chain, _ := configor.getEnvironmentChain()
+	for _, envs := range [][]string{wellKnownEnvironments, chain, configor.Config.AllowedEnvironments, configor.Config.ExampleEnvironments} {
+		for _, env := range envs {
+			names[env] = true
 		}
 	}
-	data, err := readFile(file)
+
+	for env, parent := range configor.Config.EnvironmentParents {
+		names[env], names[parent] = true, true
+	}
+	return names
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Configor) processFile(config interface{}, file string, errorOnUnmatchedKeys bool) error {
+	configurationFile, err := c.readConfigurationFile(file, c.getIncludeKey(config))
 	if err != nil {
 		return err
 	}
+	data := configurationFile.data
 
+	mergeFields, err := getMergeFields(reflect.Indirect(reflect.ValueOf(config)).Type())
+	if err != nil {
+		return err
+	} else if len(mergeFields) == 0 {
+		if err := decodeConfigurationData(config, file, data, errorOnUnmatchedKeys); err != nil {
+			return err
+		}
+		return c.decryptValues(file, data, config)
+	}
+
+	// decoders replace slices and merge maps differently, so decode the file separately,
+	// and merge its values into fields with merge strategies
+	previousValues := mergeFields.values(config)
+	for i, field := range mergeFields {
+		// literal defaults are fallbacks, replace them instead of merging into them
+		if origin, ok := c.getLoadingOrigin(field.name); ok && origin.Kind == OriginDefault {
+			previousValues[i] = reflect.Value{}
+		}
+	}
+	if err := decodeConfigurationData(config, file, data, errorOnUnmatchedKeys); err != nil {
+		return err
+	}
+
+	fileConfig := reflect.New(reflect.Indirect(reflect.ValueOf(config)).Type())
+	if err := decodeConfigurationData(fileConfig.Interface(), file, data, false); err != nil {
+		return err
+	}
+	mergeFields.merge(config, previousValues, fileConfig.Interface(), configurationFile.getKeys(file))
+	return c.decryptValues(file, data, config)
+}
+
+func decodeConfigurationData(config interface{}, file string, data []byte, errorOnUnmatchedKeys bool) error {
 	switch {
 	case strings.HasSuffix(file, ".yaml") || strings.HasSuffix(file, ".yml"):
 		if errorOnUnmatchedKeys {
@@ -212,7 +401,11 @@ func getPrefixForStruct(prefixes []string, fieldStruct *reflect.StructField) []s
 	return append(prefixes, fieldStruct.Name)
 }
 
-func (configor *Configor) processDefaults(config interface{}) error {
+// processDefaults sets `default` tag values on blank fields, literal defaults are set when computed is false,
+// computed defaults (env references, templates and default funcs) are set when computed is true, root is the
+// configuration being loaded, used as data of templates and argument of default funcs, path is the field names
+// leading to config, used to record origins of values
+func (configor *Configor) processDefaults(root, config interface{}, path []string, computed bool) error {
 	configValue := reflect.Indirect(reflect.ValueOf(config))
 	if configValue.Kind() != reflect.Struct {
 		return errors.New("invalid config, should be struct")
@@ -231,10 +424,15 @@ func (configor *Configor) processDefaults(config interface{}) error {
 
 		if isBlank := reflect.DeepEqual(field.Interface(), reflect.Zero(field.Type()).Interface()); isBlank {
 			// Set default configuration if blank
-			if value := fieldStruct.Tag.Get("default"); value != "" {
-				if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
+			if value := fieldStruct.Tag.Get("default"); value != "" && isComputedDefault(value) == computed {
+				if computed {
+					if err := configor.setComputedDefault(root, field, value); err != nil {
+						return fmt.Errorf("failed to compute default value of field %v, got %v", fieldStruct.Name, err)
+					}
+				} else if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
 					return err
 				}
+				configor.recordOrigin(append(path, fieldStruct.Name), Origin{Kind: OriginDefault})
 			}
 		}
 
@@ -244,13 +442,13 @@ func (configor *Configor) processDefaults(config interface{}) error {
 
 		switch field.Kind() {
 		case reflect.Struct:
-			if err := configor.processDefaults(field.Addr().Interface()); err != nil {
+			if err := configor.processDefaults(root, field.Addr().Interface(), append(path, fieldStruct.Name), computed); err != nil {
 				return err
 			}
 		case reflect.Slice:
 			for i := 0; i < field.Len(); i++ {
-				if reflect.Indirect(field.Index(i)).Kind() == reflect.Struct {
-					if err := configor.processDefaults(field.Index(i).Addr().Interface()); err != nil {
+				if elem := reflect.Indirect(field.Index(i)); elem.Kind() == reflect.Struct {
+					if err := configor.processDefaults(root, elem.Addr().Interface(), append(path, fieldStruct.Name, fmt.Sprint(i)), computed); err != nil {
 						return err
 					}
 				}
@@ -261,7 +459,9 @@ func (configor *Configor) processDefaults(config interface{}) error {
 	return nil
 }
 
-func (configor *Configor) processTags(config interface{}, prefixes ...string) error {
+// processTags loads fields of config from shell environments, path is the field names leading to config, used to
+// record origins of values, prefixes are used to generate env names
+func (configor *Configor) processTags(config interface{}, path []string, prefixes ...string) error {
 	configValue := reflect.Indirect(reflect.ValueOf(config))
 	if configValue.Kind() != reflect.Struct {
 		return errors.New("invalid config, should be struct")
@@ -281,45 +481,43 @@ func (configor *Configor) processTags(config interface{}, prefixes ...string) er
 		}
 
 		if envName == "" {
-			envNames = append(envNames, strings.Join(append(prefixes, fieldStruct.Name), "_"))                  // Configor_DB_Name
-			envNames = append(envNames, strings.ToUpper(strings.Join(append(prefixes, fieldStruct.Name), "_"))) // CONFIGOR_DB_NAME
+			envNames = configor.getEnvNames(append(prefixes, fieldStruct.Name)) // Configor_DB_Name, CONFIGOR_DB_NAME
 		} else {
-			envNames = []string{envName}
+			envNames = splitEnvNames(envName) // DB_PASSWORD,DATABASE_PASSWORD
 		}
+		deprecatedEnvNames := splitEnvNames(fieldStruct.Tag.Get("env_deprecated"))
 
 		if configor.Config.Verbose {
-			fmt.Printf("Trying to load struct `%v`'s field `%v` from env %v\n", configType.Name(), fieldStruct.Name, strings.Join(envNames, ", "))
+			configor.logger().Debug("Trying to load field from env", "struct", configType.Name(), "field", fieldStruct.Name, "env", strings.Join(append(envNames, deprecatedEnvNames...), ", "))
 		}
 
 		// Load From Shell ENV
-		for _, env := range envNames {
-			if value := os.Getenv(env); value != "" {
-				if configor.Config.Debug || configor.Config.Verbose {
-					fmt.Printf("Loading configuration for struct `%v`'s field `%v` from env %v...\n", configType.Name(), fieldStruct.Name, env)
-				}
-
-				switch reflect.Indirect(field).Kind() {
-				case reflect.Bool:
-					switch strings.ToLower(value) {
-					case "", "0", "f", "false":
-						field.Set(reflect.ValueOf(false))
-					default:
-						field.Set(reflect.ValueOf(true))
-					}
-				case reflect.String:
-					field.Set(reflect.ValueOf(value))
-				default:
-					if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
-						return err
-					}
-				}
-				break
+		allowEmpty := configor.Config.AllowEmptyEnv || fieldStruct.Tag.Get("env_allow_empty") == "true"
+		env, value, ok := configor.lookupEnvValue(envNames, allowEmpty)
+		if !ok {
+			if env, value, ok = configor.lookupEnvValue(deprecatedEnvNames, allowEmpty); ok && !configor.Silent {
+				configor.logger().Warn("Env is deprecated", "env", env, "struct", configType.Name(), "field", fieldStruct.Name, "replacement", strings.Join(envNames, ", "))
 			}
 		}
 
-		if isBlank := reflect.DeepEqual(field.Interface(), reflect.Zero(field.Type()).Interface()); isBlank && fieldStruct.Tag.Get("required") == "true" {
-			// return error if it is required but blank
-			return errors.New(fieldStruct.Name + " is required, but blank")
+		if ok {
+			if configor.Config.Debug || configor.Config.Verbose {
+				configor.logger().Info("Loading configuration for field from env", "struct", configType.Name(), "field", fieldStruct.Name, "env", env)
+			}
+
+			separator := fieldStruct.Tag.Get("env_separator")
+			if separator == "" {
+				separator = ","
+			}
+
+			if err := decodeEnvValue(field, value, separator); err != nil {
+				if isSensitiveField(fieldStruct) {
+					// errors of decoders might contain the value
+					return fmt.Errorf("failed to load field %v from env %v, got invalid value", fieldStruct.Name, env)
+				}
+				return fmt.Errorf("failed to load field %v from env %v, got %v", fieldStruct.Name, env, err)
+			}
+			configor.recordOrigin(append(path, fieldStruct.Name), Origin{Kind: OriginEnv, Env: env})
 		}
 
 		for field.Kind() == reflect.Ptr {
@@ -327,59 +525,107 @@ func (configor *Configor) processTags(config interface{}, prefixes ...string) er
 		}
 
 		if field.Kind() == reflect.Struct {
-			if err := configor.processTags(field.Addr().Interface(), getPrefixForStruct(prefixes, &fieldStruct)...); err != nil {
+			if err := configor.processTags(field.Addr().Interface(), append(path, fieldStruct.Name), getPrefixForStruct(prefixes, &fieldStruct)...); err != nil {
 				return err
 			}
 		}
 
-		if field.Kind() == reflect.Slice {
-			if arrLen := field.Len(); arrLen > 0 {
-				for i := 0; i < arrLen; i++ {
-					if reflect.Indirect(field.Index(i)).Kind() == reflect.Struct {
-						if err := configor.processTags(field.Index(i).Addr().Interface(), append(getPrefixForStruct(prefixes, &fieldStruct), fmt.Sprint(i))...); err != nil {
-							return err
-						}
-					}
-				}
-			} else {
-				defer func(field reflect.Value, fieldStruct reflect.StructField) {
-					if !configValue.IsZero() {
-						// load slice from env
-						newVal := reflect.New(field.Type().Elem()).Elem()
-						if newVal.Kind() == reflect.Struct {
-							idx := 0
-							for {
-								newVal = reflect.New(field.Type().Elem()).Elem()
-								if err := configor.processTags(newVal.Addr().Interface(), append(getPrefixForStruct(prefixes, &fieldStruct), fmt.Sprint(idx))...); err != nil {
-									return // err
-								} else if reflect.DeepEqual(newVal.Interface(), reflect.New(field.Type().Elem()).Elem().Interface()) {
-									break
-								} else {
-									idx++
-									field.Set(reflect.Append(field, newVal))
-								}
-							}
-						}
-					}
-				}(field, fieldStruct)
+		if field.Kind() == reflect.Map {
+			if err := configor.processMapTags(field, append(path, fieldStruct.Name), getPrefixForStruct(prefixes, &fieldStruct)); err != nil {
+				return err
+			}
+		}
+
+		if field.Kind() == reflect.Slice && !isScalarEnvType(field.Type().Elem()) && isStructType(field.Type().Elem()) {
+			if err := configor.processSliceTags(field, append(path, fieldStruct.Name), getPrefixForStruct(prefixes, &fieldStruct)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// processRequired returns error if fields tagged with `required:"true"` are blank, after loading from all sources
+func (configor *Configor) processRequired(value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			return configor.processRequired(value.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			var (
+				fieldStruct = value.Type().Field(i)
+				field       = value.Field(i)
+			)
+
+			if !field.CanInterface() {
+				continue
+			}
+
+			if isBlank := reflect.DeepEqual(field.Interface(), reflect.Zero(field.Type()).Interface()); isBlank && fieldStruct.Tag.Get("required") == "true" {
+				// return error if it is required but blank
+				return errors.New(fieldStruct.Name + " is required, but blank")
+			}
+
+			if err := configor.processRequired(field); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := configor.processRequired(value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			if err := configor.processRequired(value.MapIndex(key)); err != nil {
+				return err
 			}
 		}
 	}
 	return nil
 }
 
-func (configor *Configor) load(config interface{}, watchMode bool, files ...string) (err error, changed bool) {
+func (configor *Configor) load(config interface{}, flags []*flagValue, watchMode bool, files ...string) (err error, changed bool) {
 	defer func() {
 		if configor.Config.Debug || configor.Config.Verbose {
 			if err != nil {
-				fmt.Printf("Failed to load configuration from %v, got %v\n", files, err)
+				configor.logger().Error("Failed to load configuration", "files", stripOptionalFiles(files), "error", err)
 			}
 
-			fmt.Printf("Configuration:\n  %#v\n", config)
+			configor.logger().Info("Configuration", "config", Dump(config))
 		}
 	}()
 
-	configFiles, configModTimeMap := configor.getConfigurationFiles(configor.Config, watchMode, files...)
+	if !watchMode && (configor.Config.Debug || configor.Config.Verbose) {
+		configor.logger().Info("Current environment", "environment", configor.GetEnvironment())
+	}
+
+	if err = configor.validateEnvironment(); err != nil {
+		return err, true
+	}
+
+	var (
+		sources          = configor.getSources()
+		configModTimeMap = map[string]time.Time{}
+		l                = &loader{configor: configor, config: config, files: files, flags: flags, configFiles: map[*filesSource][]string{}}
+	)
+
+	for _, source := range sources {
+		if filesSource, ok := source.(*filesSource); ok {
+			configFiles, modTimes, err := configor.getConfigurationFiles(watchMode, configor.getIncludeKey(config), filesSource.getFiles(files)...)
+			if err != nil {
+				return err, true
+			}
+
+			l.configFiles[filesSource] = configFiles
+			for file, modTime := range modTimes {
+				configModTimeMap[file] = modTime
+			}
+		}
+	}
 
 	if watchMode {
 		if len(configModTimeMap) == len(configor.configModTimes) {
@@ -396,24 +642,35 @@ func (configor *Configor) load(config interface{}, watchMode bool, files ...stri
 		}
 	}
 
-	// process defaults
-	configor.processDefaults(config)
+	configor.originsMutex.Lock()
+	configor.loadingOrigins = map[string]Origin{}
+	configor.originsMutex.Unlock()
 
-	for _, file := range configFiles {
-		if configor.Config.Debug || configor.Config.Verbose {
-			fmt.Printf("Loading configurations from file '%v'...\n", file)
-		}
-		if err = configor.processFile(config, file, configor.GetErrorOnUnmatchedKeys()); err != nil {
+	for _, source := range sources {
+		if err = source.load(l); err != nil {
 			return err, true
 		}
 	}
 	configor.configModTimes = configModTimeMap
 
-	if prefix := configor.getENVPrefix(config); prefix == "-" {
-		err = configor.processTags(config)
-	} else {
-		err = configor.processTags(config, prefix)
+	// resolve secret references, which could be loaded from any source
+	if len(configor.Config.SecretProviders) > 0 {
+		if err = configor.processSecrets(reflect.ValueOf(config), nil); err != nil {
+			return err, true
+		}
+	}
+
+	// process computed defaults, which could depend on values loaded from all sources
+	if err = configor.processDefaults(config, config, nil, true); err != nil {
+		return err, true
+	}
+
+	if err = configor.processRequired(reflect.ValueOf(config)); err != nil {
+		return err, true
 	}
 
-	return err, true
+	configor.originsMutex.Lock()
+	configor.origins, configor.loadingOrigins = configor.loadingOrigins, nil
+	configor.originsMutex.Unlock()
+	return nil, true
 }